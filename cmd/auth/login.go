@@ -64,6 +64,11 @@ var login app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error
 	if isMiner {
 		scope = append(scope, "miner")
 	}
+	if admin, err := db.GetAccountAdmin(r, aUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if admin {
+		scope = append(scope, "admin")
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"aud":   "emrys.io",
 		"exp":   time.Now().Add(time.Hour * 24 * time.Duration(days)).Unix(),
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// deleteProject removes the user's cached project data set from disk and cloud storage
+var deleteProject app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	vars := mux.Vars(r)
+	project := vars["project"]
+	// only user-svc may delete project data, once it's checked the project has no active jobs and marked it busy
+	if busy, err := db.GetProjectBusy(r, uUUID, project); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "project not found"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if busy != db.ProjectDeleting {
+		return &app.Error{Code: http.StatusConflict, Message: "project must be deleted through the user api"}
+	}
+
+	projectDir := filepath.Join("data", uID, project)
+	unlock := lockProject(uID, project)
+	defer unlock()
+
+	if err := os.RemoveAll(projectDir); err != nil {
+		log.Sugar.Errorw("error removing project dir",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"project", project,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if projectExists(projectDir) {
+		if err := deleteCloudProject(projectDir); err != nil {
+			log.Sugar.Errorw("error deleting project from cloud storage",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"project", project,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+	}
+
+	return nil
+}
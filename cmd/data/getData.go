@@ -37,9 +37,9 @@ var getData app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Erro
 		return nil
 	}
 
-	uUUID, project, err := db.GetJobOwnerAndProject(r, jUUID)
+	uUUID, project, err := db.GetJobNamespace(r, jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error retrieving job owner and project",
+		log.Sugar.Errorw("error retrieving job namespace",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
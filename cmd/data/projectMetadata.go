@@ -1,7 +1,11 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"github.com/wminshew/emrys/pkg/check"
 	"github.com/wminshew/emrys/pkg/job"
 	"github.com/wminshew/emrysserver/pkg/app"
 	"io"
@@ -9,6 +13,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"sync"
 )
 
@@ -19,6 +24,9 @@ const (
 var (
 	mdSync   map[string]map[string]*job.FileMetadata
 	diskSync map[string]*sync.Mutex
+	// diskSyncMu guards diskSync for requests which may arrive concurrently for one project,
+	// e.g. a job array's re-runs
+	diskSyncMu sync.Mutex
 )
 
 func initMetadataSync() {
@@ -71,3 +79,49 @@ func updateProjectMetadata(r *http.Request, uID, project, relPath string, fileMd
 	}
 	return nil
 }
+
+// lockProject blocks until no other request is reading or writing the project's metadata
+// and returns a function which releases it
+func lockProject(uID, project string) func() {
+	p := filepath.Join("data", uID, project, metadataExt)
+	diskSyncMu.Lock()
+	if _, ok := diskSync[p]; !ok {
+		diskSync[p] = &sync.Mutex{}
+	}
+	m := diskSync[p]
+	diskSyncMu.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+// projectFingerprint identifies a data set by its files' relative paths and hashes
+func projectFingerprint(md map[string]*job.FileMetadata) string {
+	relPaths := make([]string, 0, len(md))
+	for relPath := range md {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+	h := sha256.New()
+	for _, relPath := range relPaths {
+		fmt.Fprintf(h, "%s\x00%s\n", relPath, md[relPath].Hash)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readProjectFingerprint returns the fingerprint of the project's data set as stored on disk
+func readProjectFingerprint(uID, project string) (string, error) {
+	unlock := lockProject(uID, project)
+	defer unlock()
+	md := map[string]*job.FileMetadata{}
+	f, err := os.Open(filepath.Join("data", uID, project, metadataExt))
+	if os.IsNotExist(err) {
+		return projectFingerprint(md), nil
+	} else if err != nil {
+		return "", err
+	}
+	defer check.Err(f.Close)
+	if err := json.NewDecoder(f).Decode(&md); err != nil && err != io.EOF {
+		return "", err
+	}
+	return projectFingerprint(md), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// renameProject moves the user's cached project data set to the name in the query on disk
+// and in cloud storage
+var renameProject app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	vars := mux.Vars(r)
+	project := vars["project"]
+	name := r.URL.Query().Get("name")
+	if !projectRegexp.MatchString(name) {
+		return &app.Error{Code: http.StatusBadRequest, Message: "invalid project name"}
+	}
+	// only user-svc may move project data, once it's checked the project has no active jobs and marked it busy
+	if busy, err := db.GetProjectBusy(r, uUUID, project); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "project not found"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if busy != db.ProjectRenaming {
+		return &app.Error{Code: http.StatusConflict, Message: "project must be renamed through the user api"}
+	}
+
+	srcDir := filepath.Join("data", uID, project)
+	dstDir := filepath.Join("data", uID, name)
+	unlockSrc := lockProject(uID, project)
+	defer unlockSrc()
+	unlockDst := lockProject(uID, name)
+	defer unlockDst()
+
+	if err := os.RemoveAll(dstDir); err != nil {
+		log.Sugar.Errorw("error removing stale project dir",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"project", name,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	if err := os.Rename(srcDir, dstDir); err != nil && !os.IsNotExist(err) {
+		log.Sugar.Errorw("error moving project dir",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"project", project,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if projectExists(dstDir) {
+		if err := deleteCloudProject(dstDir); err != nil {
+			log.Sugar.Errorw("error deleting stale project from cloud storage",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"project", name,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+	}
+	if projectExists(srcDir) {
+		if err := moveCloudProject(srcDir, dstDir); err != nil {
+			log.Sugar.Errorw("error moving project in cloud storage",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"project", project,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// rerunData marks job jID's data synced with the data set of the job in the from query,
+// provided the project's cached data set hasn't changed since
+var rerunData app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+	// org project data and images are shared, so they're stored under the org
+	uUUID, _, err := db.GetJobNamespace(r, jUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	uID := uUUID.String()
+	fromUUID, err := uuid.FromString(r.URL.Query().Get("from"))
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing from job ID"}
+	}
+
+	project := vars["project"]
+	if owner, fromProject, err := db.GetJobNamespace(r, fromUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if !uuid.Equal(owner, uUUID) || fromProject != project {
+		return &app.Error{Code: http.StatusNotFound, Message: "job not found"}
+	}
+	fromFp, err := db.GetJobDataFingerprint(r, fromUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if fromFp == "" {
+		return &app.Error{Code: http.StatusConflict, Message: fmt.Sprintf("no data set snapshot recorded for job %s", fromUUID)}
+	}
+
+	projectDir := filepath.Join("data", uID, project)
+	if _, err := os.Stat(projectDir); os.IsNotExist(err) && projectExists(projectDir) {
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			log.Sugar.Errorw("error making directory",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jID,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		if err := downloadProject(projectDir); err != nil {
+			log.Sugar.Errorw("error downloading project from gcs",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jID,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+	}
+	go func() {
+		touchProjectMd(projectDir)
+	}()
+
+	fp, err := readProjectFingerprint(uID, project)
+	if err != nil {
+		log.Sugar.Errorw("error fingerprinting project data set",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	} else if fp != fromFp {
+		return &app.Error{Code: http.StatusConflict,
+			Message: fmt.Sprintf("project data set has changed since job %s; sync data instead", fromUUID)}
+	}
+
+	if err := db.SetJobDataFingerprint(r, jUUID, fp); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	return db.SetStatusDataSynced(r, jUUID)
+}
@@ -16,14 +16,16 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"regexp"
 	"syscall"
 	"time"
 )
 
 var (
-	authSecret = os.Getenv("AUTH_SECRET")
-	debugCors  = (os.Getenv("DEBUG_CORS") == "true")
-	debugLog   = (os.Getenv("DEBUG_LOG") == "true")
+	authSecret    = os.Getenv("AUTH_SECRET")
+	debugCors     = (os.Getenv("DEBUG_CORS") == "true")
+	debugLog      = (os.Getenv("DEBUG_LOG") == "true")
+	projectRegexp *regexp.Regexp
 )
 
 func main() {
@@ -54,6 +56,7 @@ func main() {
 
 	uuidRegexpMux := validate.UUIDRegexpMux()
 	projectRegexpMux := validate.ProjectRegexpMux()
+	projectRegexp = regexp.MustCompile(fmt.Sprintf("^%s$", projectRegexpMux))
 
 	r := mux.NewRouter()
 	r.NotFoundHandler = http.HandlerFunc(app.APINotFound)
@@ -66,9 +69,15 @@ func main() {
 	rDataUser.Use(checkDataSynced)
 	syncUserPath := fmt.Sprintf("/project/{project:%s}/job/{jID}", projectRegexpMux)
 	rDataUser.Handle(syncUserPath, syncUser).Methods(http.MethodPost)
+	rDataUser.Handle(path.Join(syncUserPath, "rerun"), rerunData).Methods(http.MethodPost)
 	uploadDataPath := path.Join(syncUserPath, "{relPath:.*}")
 	rDataUser.Handle(uploadDataPath, uploadData).Methods("PUT")
 
+	rDataProject := r.PathPrefix(fmt.Sprintf("/project/{project:%s}", projectRegexpMux)).Subrouter()
+	rDataProject.Use(auth.Jwt(authSecret, []string{"user"}))
+	rDataProject.Handle("", deleteProject).Methods(http.MethodDelete)
+	rDataProject.Handle("/rename", renameProject).Methods(http.MethodPost)
+
 	rDataMiner := r.PathPrefix("/miner").Methods(http.MethodGet).Subrouter()
 	getDataPath := fmt.Sprintf("/job/{jID:%s}", uuidRegexpMux)
 	rDataMiner.Handle(getDataPath, getData)
@@ -66,3 +66,46 @@ func uploadProject(projectDir string) error {
 	}
 	return err
 }
+
+func deleteCloudProject(projectDir string) error {
+	// projectDir = /data/{uID}/{project}
+	// gsutil -m rm -r gs://emrys-dev/data/{uID}/{project}
+	// https://cloud.google.com/storage/docs/gsutil/commands/rm
+	cmdStr := "gsutil"
+	src := fmt.Sprintf("%s/%s", bkt, projectDir)
+	args := []string{"-m", "rm", "-r", src}
+	cmd := exec.Command(cmdStr, args...)
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	cmd.Env = os.Environ()
+	err := cmd.Run()
+	log.Sugar.Infof(out.String())
+	if err != nil {
+		log.Sugar.Errorf("%s: %s", err, stderr.String())
+	}
+	return err
+}
+
+func moveCloudProject(srcDir, dstDir string) error {
+	// srcDir, dstDir = /data/{uID}/{project}
+	// gsutil -m mv gs://emrys-dev/data/{uID}/{project} gs://emrys-dev/data/{uID}/{name}
+	// https://cloud.google.com/storage/docs/gsutil/commands/mv
+	cmdStr := "gsutil"
+	src := fmt.Sprintf("%s/%s", bkt, srcDir)
+	dst := fmt.Sprintf("%s/%s", bkt, dstDir)
+	args := []string{"-m", "mv", src, dst}
+	cmd := exec.Command(cmdStr, args...)
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	cmd.Env = os.Environ()
+	err := cmd.Run()
+	log.Sugar.Infof(out.String())
+	if err != nil {
+		log.Sugar.Errorf("%s: %s", err, stderr.String())
+	}
+	return err
+}
@@ -29,17 +29,12 @@ var syncUser app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Err
 		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
 	}
 
-	uID := r.Header.Get("X-Jwt-Claims-Subject")
-	_, err = uuid.FromString(uID)
+	// org project data and images are shared, so they're stored under the org
+	uUUID, _, err := db.GetJobNamespace(r, jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error parsing user ID",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-			"jID", jID,
-		)
-		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
 	}
+	uID := uUUID.String()
 
 	project := vars["project"]
 	projectDir := filepath.Join("data", uID, project)
@@ -171,6 +166,9 @@ var syncUser app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Err
 	}
 
 	if len(uploadList) == 0 {
+		if err := db.SetJobDataFingerprint(r, jUUID, projectFingerprint(serverMetadata)); err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+		}
 		return db.SetStatusDataSynced(r, jUUID)
 	}
 
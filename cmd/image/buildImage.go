@@ -48,16 +48,10 @@ var buildImage app.Handler = func(w http.ResponseWriter, r *http.Request) *app.E
 		)
 		return nil
 	}
-	uID := r.Header.Get("X-Jwt-Claims-Subject")
-	uUUID, err := uuid.FromString(uID)
+	// org project data and images are shared, so they're stored under the org
+	uUUID, _, err := db.GetJobNamespace(r, jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error parsing job ID",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-			"jID", jID,
-		)
-		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
 	}
 	nbQuery := r.URL.Query().Get("notebook")
 	notebook := (nbQuery == "1")
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/cenkalti/backoff"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrys/pkg/check"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+const manifestV2MediaType = "application/vnd.docker.distribution.manifest.v2+json"
+
+// deleteProjectImages removes every tag of the user's project image from the registry
+var deleteProjectImages app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	vars := mux.Vars(r)
+	project := vars["project"]
+	// only user-svc may delete project images, once it's checked the project has no active jobs and marked it busy
+	if busy, err := db.GetProjectBusy(r, uUUID, project); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "project not found"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if busy != db.ProjectDeleting && busy != db.ProjectRenaming {
+		return &app.Error{Code: http.StatusConflict, Message: "project must be deleted or renamed through the user api"}
+	}
+
+	repo := path.Join(uUUID.String(), project)
+
+	ctx := r.Context()
+	client := &http.Client{}
+	tags := struct {
+		Tags []string `json:"tags"`
+	}{}
+	digests := map[string]struct{}{}
+	operation := func() error {
+		u := url.URL{
+			Scheme: "http",
+			Host:   registryHost,
+			Path:   path.Join("v2", repo, "tags", "list"),
+		}
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req = req.WithContext(ctx)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer check.Err(resp.Body.Close)
+
+		if resp.StatusCode == http.StatusNotFound {
+			return nil // nothing pushed yet
+		} else if resp.StatusCode >= 500 {
+			return fmt.Errorf("registry: temporary error")
+		} else if resp.StatusCode >= 300 {
+			b, _ := ioutil.ReadAll(resp.Body)
+			return backoff.Permanent(fmt.Errorf("registry: %v", string(b)))
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+			return backoff.Permanent(fmt.Errorf("decoding tags: %v", err))
+		}
+
+		for _, tag := range tags.Tags {
+			u.Path = path.Join("v2", repo, "manifests", tag)
+			req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+			if err != nil {
+				return backoff.Permanent(err)
+			}
+			req.Header.Set("Accept", manifestV2MediaType)
+			req = req.WithContext(ctx)
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			check.Err(resp.Body.Close)
+			if resp.StatusCode == http.StatusNotFound {
+				continue
+			} else if resp.StatusCode >= 300 {
+				return fmt.Errorf("registry: %s head manifest: %v", tag, resp.Status)
+			}
+			digests[resp.Header.Get("Docker-Content-Digest")] = struct{}{}
+		}
+
+		for digest := range digests {
+			u.Path = path.Join("v2", repo, "manifests", digest)
+			req, err := http.NewRequest(http.MethodDelete, u.String(), nil)
+			if err != nil {
+				return backoff.Permanent(err)
+			}
+			req = req.WithContext(ctx)
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			check.Err(resp.Body.Close)
+			if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+				return fmt.Errorf("registry: %s delete manifest: %v", digest, resp.Status)
+			}
+			delete(digests, digest)
+		}
+
+		return nil
+	}
+	if err := backoff.RetryNotify(operation,
+		backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries), ctx),
+		func(err error, t time.Duration) {
+			log.Sugar.Errorw("error deleting project images, retrying",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"project", project,
+			)
+		}); err != nil {
+		log.Sugar.Errorw("error deleting project images--aborting",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"project", project,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	log.Sugar.Infof("Deleted %d tags of %s from registry", len(tags.Tags), repo)
+	return nil
+}
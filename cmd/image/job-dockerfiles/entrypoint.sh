@@ -28,4 +28,4 @@ if [ "$NOTEBOOK" = 'true' ]; then
   exec jupyter notebook --ip=0.0.0.0 --no-browser --port=8888 --NotebookApp.custom_display_url=http://127.0.0.1:8888
 fi
 
-exec python "$MAIN"
+exec python "$MAIN" "$@"
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/cenkalti/backoff"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrys/pkg/check"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+type manifestV2 struct {
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		Digest string `json:"digest"`
+	} `json:"layers"`
+}
+
+type rerunImageRequest struct {
+	Args []string `json:"args"`
+}
+
+// rerunImage tags the image built for the job in the from query for job jID, without rebuilding.
+// The copy happens inside the registry, so no layers pass through image-svc. If the json body
+// has args, the copy's command is replaced with them, which the job entrypoint passes to main
+var rerunImage app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	project := vars["project"]
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+	if t, err := db.GetStatusImageBuilt(r, jUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged err
+	} else if !t.IsZero() {
+		log.Sugar.Infow("user tried to re-build image",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jID,
+		)
+		return nil
+	}
+	// org project data and images are shared, so they're stored under the org
+	uUUID, _, err := db.GetJobNamespace(r, jUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	fromUUID, err := uuid.FromString(r.URL.Query().Get("from"))
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing from job ID"}
+	}
+	if owner, fromProject, err := db.GetJobNamespace(r, fromUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if !uuid.Equal(owner, uUUID) || fromProject != project {
+		return &app.Error{Code: http.StatusNotFound, Message: "job not found"}
+	}
+
+	rerunReq := rerunImageRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&rerunReq); err != nil && err != io.EOF {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error decoding request body"}
+	}
+
+	ctx := r.Context()
+	client := &http.Client{}
+	userRepo := path.Join(uUUID.String(), project)
+	minerRepo := path.Join("miner", jID)
+	notFound := false
+	operation := func() error {
+		u := url.URL{
+			Scheme: "http",
+			Host:   registryHost,
+			Path:   path.Join("v2", userRepo, "manifests", fromUUID.String()),
+		}
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Accept", manifestV2MediaType)
+		req = req.WithContext(ctx)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer check.Err(resp.Body.Close)
+		if resp.StatusCode == http.StatusNotFound {
+			notFound = true
+			return nil
+		} else if resp.StatusCode >= 300 {
+			return fmt.Errorf("registry: get manifest: %v", resp.Status)
+		}
+		manifest, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		m := manifestV2{}
+		if err := json.Unmarshal(manifest, &m); err != nil {
+			return backoff.Permanent(fmt.Errorf("decoding manifest: %v", err))
+		}
+
+		digests := []string{}
+		for _, l := range m.Layers {
+			digests = append(digests, l.Digest)
+		}
+		if rerunReq.Args == nil {
+			digests = append(digests, m.Config.Digest)
+		} else {
+			if manifest, err = putArgsConfig(ctx, client, u, userRepo, minerRepo, manifest, m.Config.Digest,
+				rerunReq.Args); err != nil {
+				return err
+			}
+		}
+		for _, digest := range digests {
+			if err := mountBlob(ctx, client, u, minerRepo, userRepo, digest); err != nil {
+				return err
+			}
+		}
+
+		for _, repoRef := range [][2]string{{minerRepo, "latest"}, {userRepo, jID}} {
+			u.Path = path.Join("v2", repoRef[0], "manifests", repoRef[1])
+			req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(manifest))
+			if err != nil {
+				return backoff.Permanent(err)
+			}
+			req.Header.Set("Content-Type", manifestV2MediaType)
+			req = req.WithContext(ctx)
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			check.Err(resp.Body.Close)
+			if resp.StatusCode != http.StatusCreated {
+				return fmt.Errorf("registry: put manifest %s:%s: %v", repoRef[0], repoRef[1], resp.Status)
+			}
+		}
+
+		return nil
+	}
+	if err := backoff.RetryNotify(operation,
+		backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries), ctx),
+		func(err error, t time.Duration) {
+			log.Sugar.Errorw("error re-tagging image, retrying",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jID,
+			)
+		}); err != nil {
+		log.Sugar.Errorw("error re-tagging image--aborting",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	if notFound {
+		return &app.Error{Code: http.StatusConflict, Message: fmt.Sprintf("no image found for job %s", fromUUID)}
+	}
+
+	return db.SetStatusImageBuilt(r, jUUID)
+}
+
+// mountBlob makes blob digest from repo from available in repo to, without copying it
+func mountBlob(ctx context.Context, client *http.Client, u url.URL, to, from, digest string) error {
+	u.Path = path.Join("v2", to, "blobs", "uploads") + "/"
+	q := url.Values{}
+	q.Set("mount", digest)
+	q.Set("from", from)
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	req = req.WithContext(ctx)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	check.Err(resp.Body.Close)
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("registry: mount blob %s: %v", digest, resp.Status)
+	}
+	return nil
+}
+
+// putArgsConfig uploads to repo to a copy of image config configDigest from repo from, with its
+// command replaced by args, and returns manifest pointing at the new config. The new config is
+// also mounted back into from so both repos can reference it
+func putArgsConfig(ctx context.Context, client *http.Client, u url.URL, from, to string, manifest []byte,
+	configDigest string, args []string) ([]byte, error) {
+	u.Path = path.Join("v2", from, "blobs", configDigest)
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	req = req.WithContext(ctx)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer check.Err(resp.Body.Close)
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("registry: get config %s: %v", configDigest, resp.Status)
+	}
+	imgConfig := map[string]interface{}{}
+	if err := json.NewDecoder(resp.Body).Decode(&imgConfig); err != nil {
+		return nil, backoff.Permanent(fmt.Errorf("decoding image config: %v", err))
+	}
+	containerConfig, ok := imgConfig["config"].(map[string]interface{})
+	if !ok {
+		return nil, backoff.Permanent(fmt.Errorf("image config has no container config"))
+	}
+	containerConfig["Cmd"] = args
+	config, err := json.Marshal(imgConfig)
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(config))
+
+	u.Path = path.Join("v2", to, "blobs", "uploads") + "/"
+	req, err = http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	req = req.WithContext(ctx)
+	resp, err = client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	check.Err(resp.Body.Close)
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("registry: start config upload: %v", resp.Status)
+	}
+	location, err := u.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	q := location.Query()
+	q.Set("digest", digest)
+	location.RawQuery = q.Encode()
+	req, err = http.NewRequest(http.MethodPut, location.String(), bytes.NewReader(config))
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req = req.WithContext(ctx)
+	resp, err = client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	check.Err(resp.Body.Close)
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("registry: upload config: %v", resp.Status)
+	}
+	if err := mountBlob(ctx, client, u, from, to, digest); err != nil {
+		return nil, err
+	}
+
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, backoff.Permanent(fmt.Errorf("decoding manifest: %v", err))
+	}
+	manifestConfig, ok := m["config"].(map[string]interface{})
+	if !ok {
+		return nil, backoff.Permanent(fmt.Errorf("manifest has no config"))
+	}
+	manifestConfig["digest"] = digest
+	manifestConfig["size"] = len(config)
+	return json.Marshal(m)
+}
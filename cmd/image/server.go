@@ -52,6 +52,9 @@ func main() {
 	r.NotFoundHandler = http.HandlerFunc(app.APINotFound)
 	r.HandleFunc("/healthz", app.HealthCheck).Methods(http.MethodGet)
 
+	deleteProjectImagesPath := fmt.Sprintf("/image/{project:%s}", projectRegexpMux)
+	r.Handle(deleteProjectImagesPath, auth.Jwt(authSecret, []string{"user"})(deleteProjectImages)).Methods(http.MethodDelete)
+
 	rImage := r.PathPrefix("/image").Methods(http.MethodPost).Subrouter()
 
 	rImageMiner := rImage.PathPrefix("/downloaded").Subrouter()
@@ -68,6 +71,7 @@ func main() {
 	rImageUser.Use(auth.JobActive)
 	postBuildImagePath := fmt.Sprintf("/{jID:%s}", uuidRegexpMux)
 	rImageUser.Handle(postBuildImagePath, buildImage)
+	rImageUser.Handle(postBuildImagePath+"/rerun", rerunImage)
 
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{
@@ -4,15 +4,16 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/satori/go.uuid"
 	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
 	"github.com/wminshew/emrysserver/pkg/storage"
-	"io"
 	"net/http"
 	"os"
 	"path"
 )
 
-// downloadOutputLog downloads the miner's container execution log
+// downloadOutputLog downloads the miner's container execution log, optionally
+// searched (grep, regex), paged (from, to), tailed (tail) and gzipped (gzip)
 var downloadOutputLog app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
 	vars := mux.Vars(r)
 	jID := vars["jID"]
@@ -26,6 +27,28 @@ var downloadOutputLog app.Handler = func(w http.ResponseWriter, r *http.Request)
 		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
 	}
 
+	if tDeleted, err := db.GetStatusOutputDeleted(r, jUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if !tDeleted.IsZero() {
+		log.Sugar.Infow("user tried to download expired output log",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusGone, Message: "output log for this job has expired and was deleted per your account's retention policy"}
+	}
+
+	lf, err := parseLogFilter(r.URL.Query())
+	if err != nil {
+		log.Sugar.Infow("error parsing log filter",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+
 	p := path.Join("output", jID, "log")
 	if _, err := os.Stat(p); os.IsNotExist(err) {
 		log.Sugar.Infow("error finding output log on disk",
@@ -33,7 +56,7 @@ var downloadOutputLog app.Handler = func(w http.ResponseWriter, r *http.Request)
 			"url", r.URL,
 			"jID", jID,
 		)
-		return getOutputLogCloud(w, r, jUUID, p)
+		return getOutputLogCloud(w, r, jUUID, p, lf)
 	} else if err != nil {
 		log.Sugar.Errorw("error stating output log",
 			"method", r.Method,
@@ -54,20 +77,12 @@ var downloadOutputLog app.Handler = func(w http.ResponseWriter, r *http.Request)
 		)
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 	}
-	if _, err = io.Copy(w, f); err != nil {
-		log.Sugar.Errorw("error copying output log to response",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-			"jID", jID,
-		)
-		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
-	}
+	defer app.CheckErr(r, f.Close)
 
-	return nil
+	return writeOutputLog(w, r, jUUID, f, lf)
 }
 
-func getOutputLogCloud(w http.ResponseWriter, r *http.Request, jUUID uuid.UUID, p string) *app.Error {
+func getOutputLogCloud(w http.ResponseWriter, r *http.Request, jUUID uuid.UUID, p string, lf *logFilter) *app.Error {
 	ctx := r.Context()
 	or, err := storage.NewReader(ctx, p)
 	if err == storage.ErrObjectNotExist {
@@ -88,15 +103,7 @@ func getOutputLogCloud(w http.ResponseWriter, r *http.Request, jUUID uuid.UUID,
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 	}
 
-	if _, err = io.Copy(w, or); err != nil {
-		log.Sugar.Errorw("error copying cloud reader to response",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-			"jID", jUUID,
-		)
-		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
-	}
+	defer app.CheckErr(r, or.Close)
 
-	return nil
+	return writeOutputLog(w, r, jUUID, or, lf)
 }
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/cenkalti/backoff"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrys/pkg/check"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	jobLimitsPeriod       = 30 * time.Second
+	jobLimitWarningBuffer = 5 * time.Minute
+	maxRetries            = 10
+)
+
+// cancelingJobs holds the jobs over their limit being canceled, so each is only canceled once at
+// a time. Jobs are only marked as having reached their limit once the cancel succeeds, so failed
+// cancels are retried next period
+var cancelingJobs sync.Map
+
+type limitedJob struct {
+	jUUID      uuid.UUID
+	uUUID      uuid.UUID
+	project    string
+	notebook   bool
+	rate       float64
+	createdAt  time.Time
+	billedSec  float64
+	maxRuntime int64
+	maxSpend   int64
+	warned     bool
+}
+
+// remaining returns how long job j can keep running before hitting its runtime or spend limit.
+// Runtime counts from the job's creation; spend only accrues while it's billed for running
+func (j *limitedJob) remaining(now time.Time) time.Duration {
+	remaining := time.Duration(math.MaxInt64)
+	if j.maxRuntime > 0 {
+		remaining = time.Duration(j.maxRuntime)*time.Second - now.Sub(j.createdAt)
+	}
+	if j.maxSpend > 0 && j.rate > 0 {
+		// rate is $/hr, maxSpend is cents
+		budget := time.Duration(float64(j.maxSpend) / (j.rate * 100) * float64(time.Hour))
+		billed := time.Duration(j.billedSec * float64(time.Second))
+		if budget-billed < remaining {
+			remaining = budget - billed
+		}
+	}
+	return remaining
+}
+
+// enforceJobLimits warns users whose jobs are about to hit their max runtime or max spend,
+// and cancels jobs which have
+func enforceJobLimits() error {
+	rows, err := db.GetJobsWithLimits()
+	if err != nil {
+		return err // already logged
+	}
+	jobs := []*limitedJob{}
+	if err := func() error {
+		defer func() {
+			if err := rows.Close(); err != nil {
+				log.Sugar.Errorf("Error closing rows")
+			}
+		}()
+		for rows.Next() {
+			j := &limitedJob{}
+			if err := rows.Scan(&j.jUUID, &j.uUUID, &j.project, &j.notebook, &j.rate, &j.createdAt, &j.billedSec,
+				&j.maxRuntime, &j.maxSpend, &j.warned); err != nil {
+				return err
+			}
+			jobs = append(jobs, j)
+		}
+		return rows.Err()
+	}(); err != nil {
+		return fmt.Errorf("scanning jobs with limits: %v", err)
+	}
+
+	now := time.Now()
+	for _, j := range jobs {
+		remaining := j.remaining(now)
+		if remaining <= 0 {
+			if _, canceling := cancelingJobs.LoadOrStore(j.jUUID, true); canceling {
+				continue
+			}
+			warnJobLog(j.jUUID, "emrys: job reached its max runtime or max spend, canceling...\n")
+			go func(j *limitedJob) {
+				defer cancelingJobs.Delete(j.jUUID)
+				if err := cancelJobOverLimit(j); err != nil {
+					return // already logged
+				}
+				if err := db.SetJobLimitReached(j.jUUID); err != nil {
+					return // already logged
+				}
+			}(j)
+		} else if remaining <= jobLimitWarningBuffer && !j.warned {
+			if err := db.SetJobLimitWarned(j.jUUID); err != nil {
+				continue // already logged
+			}
+			warnJobLog(j.jUUID, fmt.Sprintf("emrys: job will reach its max runtime or max spend in %v and be canceled\n",
+				remaining.Round(time.Second)))
+		}
+	}
+	return nil
+}
+
+// warnJobLog appends msg to the job's output log and publishes it to the user's log stream
+func warnJobLog(jUUID uuid.UUID, msg string) {
+	jID := jUUID.String()
+	outputLog := path.Join("output", jID, "log")
+	if f, err := os.OpenFile(outputLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		log.Sugar.Errorw("error opening output log",
+			"err", err.Error(),
+			"jID", jID,
+		)
+	} else {
+		if _, err := f.WriteString(msg); err != nil {
+			log.Sugar.Errorw("error writing limit warning to output log",
+				"err", err.Error(),
+				"jID", jID,
+			)
+		}
+		check.Err(f.Close)
+	}
+
+	if err := jobsManager.Publish(jID, []byte(msg)); err != nil {
+		log.Sugar.Errorw("error publishing limit warning",
+			"err", err.Error(),
+			"jID", jID,
+		)
+	}
+}
+
+// cancelJobOverLimit cancels job j on behalf of its owner via user-svc
+func cancelJobOverLimit(j *limitedJob) error {
+	log.Sugar.Infow("job limit reached, canceling job",
+		"jID", j.jUUID,
+	)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"aud":   "emrys.io",
+		"exp":   time.Now().Add(time.Minute * 5).Unix(),
+		"iss":   "emrys.io",
+		"iat":   time.Now().Unix(),
+		"sub":   j.uUUID,
+		"scope": []string{"user"},
+	})
+	authToken, err := token.SignedString([]byte(authSecret))
+	if err != nil {
+		log.Sugar.Errorw("error signing token",
+			"err", err.Error(),
+			"jID", j.jUUID,
+		)
+		return err
+	}
+
+	ctx := context.Background()
+	client := &http.Client{}
+	p := path.Join("user", "project", j.project, "job", j.jUUID.String(), "cancel")
+	u := url.URL{
+		Scheme: "http",
+		Host:   "user-svc:8080",
+		Path:   p,
+	}
+	if j.notebook {
+		q := u.Query()
+		q.Set("notebook", "1")
+		u.RawQuery = q.Encode()
+	}
+	operation := func() error {
+		req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", authToken))
+		req = req.WithContext(ctx)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer check.Err(resp.Body.Close)
+
+		if resp.StatusCode == http.StatusBadGateway {
+			return fmt.Errorf("server: temporary error")
+		} else if resp.StatusCode >= 300 {
+			b, _ := ioutil.ReadAll(resp.Body)
+			return backoff.Permanent(fmt.Errorf("server: %v", string(b)))
+		}
+
+		return nil
+	}
+	if err := backoff.RetryNotify(operation,
+		backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries), ctx),
+		func(err error, t time.Duration) {
+			log.Sugar.Errorw("error posting limit cancellation to user-svc, retrying",
+				"err", err.Error(),
+				"jID", j.jUUID,
+			)
+		}); err != nil {
+		log.Sugar.Errorw("error posting limit cancellation to user-svc--aborting",
+			"err", err.Error(),
+			"jID", j.jUUID,
+		)
+		return err
+	}
+	return nil
+}
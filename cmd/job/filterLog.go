@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/ring"
+	"fmt"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// logFilter selects lines from an output log. Line numbers start at 1 and refer to
+// the unfiltered log; from/to bound the searched range, tail keeps the last matches
+type logFilter struct {
+	grep        string
+	regex       *regexp.Regexp
+	from        int64
+	to          int64
+	tail        int
+	lineNumbers bool
+	gzip        bool
+}
+
+// parseLogFilter parses grep, regex, from, to, tail, line-numbers and gzip from q
+func parseLogFilter(q url.Values) (*logFilter, error) {
+	lf := &logFilter{
+		grep:        q.Get("grep"),
+		lineNumbers: q.Get("line-numbers") == "1",
+		gzip:        q.Get("gzip") == "1",
+	}
+	var err error
+	if expr := q.Get("regex"); expr != "" {
+		if lf.regex, err = regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("invalid regex: %v", err)
+		}
+	}
+	if fromStr := q.Get("from"); fromStr != "" {
+		if lf.from, err = strconv.ParseInt(fromStr, 10, 64); err != nil || lf.from < 1 {
+			return nil, fmt.Errorf("from must be a positive line number")
+		}
+	}
+	if toStr := q.Get("to"); toStr != "" {
+		if lf.to, err = strconv.ParseInt(toStr, 10, 64); err != nil || lf.to < 1 {
+			return nil, fmt.Errorf("to must be a positive line number")
+		} else if lf.to < lf.from {
+			return nil, fmt.Errorf("to must not be less than from")
+		}
+	}
+	if tailStr := q.Get("tail"); tailStr != "" {
+		if lf.tail, err = strconv.Atoi(tailStr); err != nil || lf.tail < 1 {
+			return nil, fmt.Errorf("tail must be a positive number of lines")
+		}
+	}
+	return lf, nil
+}
+
+// passthrough returns whether the filter selects the whole log unchanged
+func (lf *logFilter) passthrough() bool {
+	return lf.grep == "" && lf.regex == nil && lf.from == 0 && lf.to == 0 && lf.tail == 0 && !lf.lineNumbers
+}
+
+func (lf *logFilter) match(line string) bool {
+	if lf.grep != "" && !strings.Contains(line, lf.grep) {
+		return false
+	}
+	if lf.regex != nil && !lf.regex.MatchString(line) {
+		return false
+	}
+	return true
+}
+
+type logLine struct {
+	n    int64
+	text string
+}
+
+// copy writes the lines of src selected by lf to dst
+func (lf *logFilter) copy(dst io.Writer, src io.Reader) error {
+	var tail *ring.Ring
+	if lf.tail > 0 {
+		tail = ring.New(lf.tail)
+	}
+	write := func(l logLine) error {
+		if !strings.HasSuffix(l.text, "\n") {
+			l.text += "\n"
+		}
+		if lf.lineNumbers {
+			l.text = fmt.Sprintf("%d:%s", l.n, l.text)
+		}
+		_, err := io.WriteString(dst, l.text)
+		return err
+	}
+
+	br := bufio.NewReader(src)
+	for n := int64(1); ; n++ {
+		line, err := br.ReadString('\n')
+		if line != "" && (lf.from == 0 || n >= lf.from) && lf.match(line) {
+			if tail != nil {
+				tail.Value = logLine{n, line}
+				tail = tail.Next()
+			} else if err := write(logLine{n, line}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || (lf.to != 0 && n >= lf.to) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if tail != nil {
+		var err error
+		tail.Do(func(v interface{}) {
+			if v == nil || err != nil {
+				return
+			}
+			err = write(v.(logLine))
+		})
+		return err
+	}
+	return nil
+}
+
+// writeOutputLog writes the lines of the output log rd selected by lf to the response,
+// gzipped if requested
+func writeOutputLog(w http.ResponseWriter, r *http.Request, jUUID uuid.UUID, rd io.Reader, lf *logFilter) *app.Error {
+	var dst io.Writer = w
+	if lf.gzip {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.log.gz", jUUID))
+		gw := gzip.NewWriter(w)
+		defer app.CheckErr(r, gw.Close)
+		dst = gw
+	}
+
+	var err error
+	if lf.passthrough() {
+		_, err = io.Copy(dst, rd)
+	} else {
+		err = lf.copy(dst, rd)
+	}
+	if err != nil {
+		log.Sugar.Errorw("error copying output log to response",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/storage"
+	"io"
+	"net/http"
+	"os"
+	"path"
+)
+
+// getCheckpoint streams a named checkpoint posted by the miner to the user
+var getCheckpoint app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+	name := vars["name"]
+
+	if tDeleted, err := db.GetStatusOutputDeleted(r, jUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if !tDeleted.IsZero() {
+		log.Sugar.Infow("user tried to download expired checkpoint",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusGone, Message: "checkpoints for this job have expired and were deleted per your account's retention policy"}
+	}
+
+	p := path.Join("output", jID, "checkpoints", name)
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return getCheckpointCloud(w, r, jUUID, p)
+	} else if err != nil {
+		log.Sugar.Errorw("error opening checkpoint",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+			"name", name,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	defer app.CheckErr(r, f.Close)
+
+	if _, err = io.Copy(w, f); err != nil {
+		log.Sugar.Errorw("error copying checkpoint to response",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+			"name", name,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
+
+func getCheckpointCloud(w http.ResponseWriter, r *http.Request, jUUID uuid.UUID, p string) *app.Error {
+	ctx := r.Context()
+	or, err := storage.NewReader(ctx, p)
+	if err == storage.ErrObjectNotExist {
+		log.Sugar.Infow("error finding checkpoint in cloud",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jUUID,
+			"path", p,
+		)
+		return &app.Error{Code: http.StatusNotFound, Message: "checkpoint not found"}
+	} else if err != nil {
+		log.Sugar.Errorw("error reading from cloud storage",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jUUID,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	defer app.CheckErr(r, or.Close)
+
+	if _, err = io.Copy(w, or); err != nil {
+		log.Sugar.Errorw("error copying cloud reader to response",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
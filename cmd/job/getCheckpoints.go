@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+type checkpoint struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	PostedAt time.Time `json:"postedAt"`
+}
+
+// getCheckpoints lists the checkpoints the miner has posted for the job
+var getCheckpoints app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+
+	checkpoints := []checkpoint{}
+	rows, err := db.GetJobCheckpoints(jUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		c := checkpoint{}
+		if err = rows.Scan(&c.Name, &c.Size, &c.PostedAt); err != nil {
+			log.Sugar.Errorw("error scanning job checkpoints",
+				"err", err.Error(),
+				"jID", jID,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		checkpoints = append(checkpoints, c)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning job checkpoints",
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := json.NewEncoder(w).Encode(&checkpoints); err != nil {
+		log.Sugar.Errorw("error encoding job checkpoints",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"strconv"
+)
+
+// getMetrics returns the job's metric points, optionally filtered by name and starting step
+var getMetrics app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+
+	q := r.URL.Query()
+	name := q.Get("name")
+	var sinceStep int64
+	if sinceStepStr := q.Get("since-step"); sinceStepStr != "" {
+		if sinceStep, err = strconv.ParseInt(sinceStepStr, 10, 64); err != nil {
+			return &app.Error{Code: http.StatusBadRequest, Message: "since-step must be an integer"}
+		}
+	}
+
+	metrics := []db.Metric{}
+	rows, err := db.GetJobMetrics(jUUID, name, sinceStep)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		m := db.Metric{}
+		if err = rows.Scan(&m.Step, &m.Name, &m.Value, &m.Time); err != nil {
+			log.Sugar.Errorw("error scanning job metrics",
+				"err", err.Error(),
+				"jID", jID,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		metrics = append(metrics, m)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning job metrics",
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := json.NewEncoder(w).Encode(&metrics); err != nil {
+		log.Sugar.Errorw("error encoding job metrics",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -44,6 +44,17 @@ var getOutputData app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 		return &app.Error{Code: http.StatusPreconditionFailed, Message: "miner failed job, no output data available"}
 	}
 
+	if tDeleted, err := db.GetStatusOutputDeleted(r, jUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if !tDeleted.IsZero() {
+		log.Sugar.Infow("user tried to download expired output data",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusGone, Message: "output data for this job has expired and was deleted per your account's retention policy"}
+	}
+
 	p := path.Join("output", jID, "data.tar.gz")
 	if _, err := os.Stat(p); os.IsNotExist(err) {
 		log.Sugar.Infow("error finding output data.tar.gz on disk",
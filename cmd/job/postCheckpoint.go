@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/cenkalti/backoff"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/storage"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	checkpointNameRegexpMux = `[A-Za-z0-9_-][A-Za-z0-9._-]{0,127}`
+	// checkpointStreamName is reserved for the user's checkpoint stream route
+	checkpointStreamName = "stream"
+)
+
+// checkpointUploads holds a *sync.Mutex per checkpoint path, so re-posts of the same checkpoint
+// upload to cloud storage and clean up their local copy one at a time
+var checkpointUploads sync.Map
+
+// postCheckpoint receives a named intermediate artifact from the miner while the job is running
+var postCheckpoint app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+	name := vars["name"]
+	if name == checkpointStreamName {
+		log.Sugar.Infow("miner tried to post checkpoint with reserved name",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("checkpoint name %s is reserved", checkpointStreamName)}
+	}
+
+	if tDataDownloaded, err := db.GetStatusDataDownloaded(r, jUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // err already logged
+	} else if tDataDownloaded.IsZero() {
+		log.Sugar.Infow("miner tried to post checkpoint before downloading data",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "must successfully download data before posting checkpoints"}
+	}
+
+	checkpointDir := path.Join("output", jID, "checkpoints")
+	if err := os.MkdirAll(checkpointDir, 0755); err != nil {
+		log.Sugar.Errorw("error making checkpoint dir",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	// write to a temp file unique to this post and rename it into place once complete, so a
+	// concurrent re-post of the same name never sees a partial or removed file
+	p := path.Join(checkpointDir, name)
+	tmp := path.Join(checkpointDir, fmt.Sprintf(".%s.%s", name, uuid.NewV4()))
+	f, err := os.Create(tmp)
+	if err != nil {
+		log.Sugar.Errorw("error creating checkpoint",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+			"name", name,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	n, err := io.Copy(f, r.Body)
+	if err != nil {
+		log.Sugar.Errorw("error copying checkpoint to file",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+			"name", name,
+		)
+		app.CheckErr(r, f.Close)
+		app.CheckErr(r, func() error { return os.Remove(tmp) })
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	app.CheckErr(r, f.Close)
+	if err := os.Rename(tmp, p); err != nil {
+		log.Sugar.Errorw("error renaming checkpoint into place",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+			"name", name,
+		)
+		app.CheckErr(r, func() error { return os.Remove(tmp) })
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := db.InsertCheckpoint(r, jUUID, name, n); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	go func() {
+		// uploads of the same checkpoint run one at a time, each from whatever was last renamed
+		// into place, so the object in cloud storage always ends up the latest post
+		mu, _ := checkpointUploads.LoadOrStore(p, &sync.Mutex{})
+		mu.(*sync.Mutex).Lock()
+		defer mu.(*sync.Mutex).Unlock()
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			return // a later post has already been uploaded and its local copy removed
+		}
+
+		ctx := context.Background()
+		var uploaded os.FileInfo
+		operation := func() error {
+			f, err := os.Open(p)
+			if err != nil {
+				return fmt.Errorf("opening checkpoint: %v", err)
+			}
+			defer app.CheckErr(r, f.Close)
+			if uploaded, err = f.Stat(); err != nil {
+				return fmt.Errorf("stating checkpoint: %v", err)
+			}
+			ow := storage.NewWriter(ctx, p)
+			defer app.CheckErr(r, ow.Close)
+			if _, err = io.Copy(ow, f); err != nil {
+				return fmt.Errorf("copying checkpoint to cloud storage object writer: %v", err)
+			}
+			return nil
+		}
+		expBackOff := backoff.NewExponentialBackOff()
+		expBackOff.MaxElapsedTime = maxBackOffElapsedTime
+		if err := backoff.RetryNotify(operation,
+			backoff.WithContext(expBackOff, ctx),
+			func(err error, t time.Duration) {
+				log.Sugar.Errorw("error uploading checkpoint to gcs--retrying",
+					"method", r.Method,
+					"url", r.URL,
+					"err", err.Error(),
+					"jID", jID,
+					"name", name,
+				)
+			}); err != nil {
+			log.Sugar.Errorw("error uploading checkpoint to gcs--abort",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jID,
+				"name", name,
+			)
+			return
+		}
+		go func() {
+			time.Sleep(15 * time.Minute)
+			mu.(*sync.Mutex).Lock()
+			defer mu.(*sync.Mutex).Unlock()
+			// no need to cache locally, unless a later post has replaced the file uploaded
+			if fi, err := os.Stat(p); err == nil && os.SameFile(fi, uploaded) {
+				app.CheckErr(r, func() error { return os.Remove(p) })
+			}
+		}()
+	}()
+
+	if err := jobsManager.Publish(fmt.Sprintf("%s-checkpoint", jID), checkpoint{
+		Name:     name,
+		Size:     n,
+		PostedAt: time.Now(),
+	}); err != nil {
+		log.Sugar.Errorw("error publishing checkpoint",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+			"name", name,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"math"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+const (
+	maxMetricsPerPost = 1000
+)
+
+var metricNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_./-]{1,128}$`)
+
+// postMetrics receives a batch of structured metric points from the miner's container
+var postMetrics app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+
+	metrics := []db.Metric{}
+	if err := json.NewDecoder(r.Body).Decode(&metrics); err != nil {
+		log.Sugar.Errorw("error decoding json request body",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing json request body"}
+	}
+	if len(metrics) == 0 {
+		return nil
+	} else if len(metrics) > maxMetricsPerPost {
+		return &app.Error{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("at most %d metric points per request", maxMetricsPerPost)}
+	}
+
+	now := time.Now()
+	for i, m := range metrics {
+		if !metricNameRegexp.MatchString(m.Name) {
+			return &app.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid metric name: %q", m.Name)}
+		}
+		if math.IsNaN(m.Value) || math.IsInf(m.Value, 0) {
+			return &app.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("metric %s at step %d must be finite", m.Name, m.Step)}
+		}
+		if m.Time.IsZero() {
+			metrics[i].Time = now
+		}
+	}
+
+	if err := db.InsertJobMetrics(r, jUUID, metrics); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	if err := jobsManager.Publish(fmt.Sprintf("%s-metrics", jID), metrics); err != nil {
+		log.Sugar.Errorw("error publishing metrics",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+	}
+
+	return nil
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/wminshew/emrysserver/pkg/app"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
-	"github.com/wminshew/emrysserver/pkg/payments"
+	"github.com/wminshew/emrysserver/pkg/notify"
 	"github.com/wminshew/emrysserver/pkg/storage"
 	"io"
 	"net/http"
@@ -18,10 +18,6 @@ import (
 	"time"
 )
 
-const (
-	jobFinishedBuffer = 5 * time.Second
-)
-
 // postOutputData receives the miner's container execution for the user
 var postOutputData app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
 	vars := mux.Vars(r)
@@ -71,7 +67,8 @@ var postOutputData app.Handler = func(w http.ResponseWriter, r *http.Request) *a
 		)
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 	}
-	if _, err = io.Copy(f, r.Body); err != nil {
+	n, err := io.Copy(f, r.Body)
+	if err != nil {
 		log.Sugar.Errorw("error copying data.tar.gz to file",
 			"method", r.Method,
 			"url", r.URL,
@@ -118,6 +115,14 @@ var postOutputData app.Handler = func(w http.ResponseWriter, r *http.Request) *a
 			)
 			return
 		}
+		if err := db.AddJobOutputBytes(jUUID, n); err != nil {
+			log.Sugar.Errorw("error adding output data bytes",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jID,
+			)
+		}
 		go func() {
 			defer app.CheckErr(r, func() error { return os.Remove(p) }) // no need to cache locally
 			time.Sleep(15 * time.Minute)
@@ -147,10 +152,9 @@ var postOutputData app.Handler = func(w http.ResponseWriter, r *http.Request) *a
 		return nil
 	}
 
-	// TODO: fix race condition between user pulling last logs / data & miner finishing job which makes it inactive
-	time.Sleep(jobFinishedBuffer)
-
-	if err := db.SetJobFinishedAndStatusOutputDataPosted(r, jUUID); err != nil {
+	if err := db.SetJobFinishedAndStatusOutputDataPosted(r, jUUID, db.PaymentTaskChargeUser, db.PaymentTaskPayMiner); err == db.ErrInvalidJobStateTransition {
+		return &app.Error{Code: http.StatusConflict, Message: "job has already ended"} // already logged
+	} else if err != nil {
 		log.Sugar.Errorw("error setting job finished and output data posted status",
 			"method", r.Method,
 			"url", r.URL,
@@ -160,8 +164,7 @@ var postOutputData app.Handler = func(w http.ResponseWriter, r *http.Request) *a
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 	}
 
-	go payments.ChargeUser(r, stripeInvoiceItemC, jUUID)
-	go payments.PayMiner(r, stripeTransferC, jUUID)
+	go notify.JobEnded(jUUID, db.JobStateFinished)
 
 	return nil
 }
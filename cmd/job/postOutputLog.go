@@ -84,11 +84,12 @@ var postOutputLog app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 			defer app.CheckErr(r, f.Close)
 
 			ctx := context.Background()
+			var n int64
 			operation := func() error {
 				uploadLog := path.Join("output", jID, "log")
 				ow := storage.NewWriter(ctx, uploadLog)
 				defer app.CheckErr(r, ow.Close)
-				if _, err = io.Copy(ow, f); err != nil {
+				if n, err = io.Copy(ow, f); err != nil {
 					return fmt.Errorf("copying log file to cloud storage object writer: %v", err)
 				}
 				return nil
@@ -113,6 +114,14 @@ var postOutputLog app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 				)
 				return
 			}
+			if err := db.AddJobOutputBytes(jUUID, n); err != nil {
+				log.Sugar.Errorw("error adding output log bytes",
+					"method", r.Method,
+					"url", r.URL,
+					"err", err.Error(),
+					"jID", jID,
+				)
+			}
 			defer func() {
 				go func() {
 					time.Sleep(15 * time.Minute)
@@ -14,6 +14,7 @@ import (
 	"github.com/wminshew/emrysserver/pkg/auth"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
 	"github.com/wminshew/emrysserver/pkg/storage"
 	"net/http"
 	"os"
@@ -42,6 +43,33 @@ func main() {
 	defer db.Close()
 	storage.Init()
 	initJobsManager()
+	initOutputSweeper()
+	done := make(chan struct{}, 1)
+	go func() {
+		for {
+			if err := sweepExpiredOutputs(); err != nil {
+				log.Sugar.Errorf("Error sweeping expired outputs: %v\n", err)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Duration(outputSweepPeriodSec) * time.Second):
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			if err := enforceJobLimits(); err != nil {
+				log.Sugar.Errorf("Error enforcing job limits: %v\n", err)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(jobLimitsPeriod):
+			}
+		}
+	}()
 
 	stripeConfig := &stripe.BackendConfig{
 		// MaxNetworkRetries: maxRetries, TODO
@@ -55,6 +83,22 @@ func main() {
 		B:   stripe.GetBackendWithConfig(stripe.APIBackend, stripeConfig),
 		Key: stripeSecretKey,
 	}
+	paymentsWorker := &payments.Worker{
+		InvoiceItemC: stripeInvoiceItemC,
+		TransferC:    stripeTransferC,
+	}
+	go func() {
+		for {
+			if err := paymentsWorker.Run(); err != nil {
+				log.Sugar.Errorf("Error running payment tasks: %v\n", err)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(payments.WorkerPeriod):
+			}
+		}
+	}()
 
 	uuidRegexpMux := validate.UUIDRegexpMux()
 
@@ -71,15 +115,22 @@ func main() {
 	rJobMiner.Use(auth.JobActive)
 	rJobMiner.Handle("/log", postOutputLog).Methods(http.MethodPost)
 	rJobMiner.Handle("/data", postOutputData).Methods(http.MethodPost)
+	rJobMiner.Handle(fmt.Sprintf("/checkpoint/{name:%s}", checkpointNameRegexpMux), postCheckpoint).Methods(http.MethodPost)
+	rJobMiner.Handle("/metrics", postMetrics).Methods(http.MethodPost)
 	rJobMiner.Handle("/cancel", getJobCancel).Methods(http.MethodGet)
 
 	rJobUser := rJob.NewRoute().Subrouter()
 	rJobUser.Use(auth.Jwt(authSecret, []string{"user"}))
 	rJobUser.Use(auth.UserJobMiddleware)
-	rJobUser.Handle("/log", auth.JobActive(streamOutputLog)).Methods(http.MethodGet)
+	rJobUser.Handle("/log", streamOutputLog).Methods(http.MethodGet)
 	rJobUser.Handle("/log/download", downloadOutputLog).Methods(http.MethodGet)
 	rJobUser.Handle("/data", getOutputData).Methods(http.MethodGet)
 	rJobUser.Handle("/data/posted", getJobOutputDataPosted).Methods(http.MethodGet) // TODO: add JobActive mdlwre?
+	rJobUser.Handle("/checkpoint", getCheckpoints).Methods(http.MethodGet)
+	rJobUser.Handle("/checkpoint/"+checkpointStreamName, streamCheckpoints).Methods(http.MethodGet)
+	rJobUser.Handle(fmt.Sprintf("/checkpoint/{name:%s}", checkpointNameRegexpMux), getCheckpoint).Methods(http.MethodGet)
+	rJobUser.Handle("/metrics", getMetrics).Methods(http.MethodGet)
+	rJobUser.Handle("/metrics/stream", streamMetrics).Methods(http.MethodGet)
 	rJobUser.Handle("/cancel", postJobCancel).Methods(http.MethodPost)
 
 	c := cors.New(cors.Options{
@@ -112,6 +163,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 	<-stop
+	close(done)
 	if err := server.Shutdown(ctx); err != nil {
 		log.Sugar.Errorf("shutting server down: %v", err)
 	}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/storage"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+var (
+	outputSweepPeriodSecStr = os.Getenv("OUTPUT_SWEEP_PERIOD_SEC")
+	outputSweepPeriodSec    int
+	outputObjects           = []string{"log", "data.tar.gz"}
+)
+
+func initOutputSweeper() {
+	var err error
+	if outputSweepPeriodSec, err = strconv.Atoi(outputSweepPeriodSecStr); err != nil {
+		panic(fmt.Errorf("converting OUTPUT_SWEEP_PERIOD_SEC to integer"))
+	}
+}
+
+// sweepExpiredOutputs deletes job outputs that have expired under their owner's retention policy
+func sweepExpiredOutputs() error {
+	rows, err := db.GetExpiredOutputs()
+	if err != nil {
+		return err // already logged
+	}
+	jUUIDs := []uuid.UUID{}
+	if err := func() error {
+		defer func() {
+			if err := rows.Close(); err != nil {
+				log.Sugar.Errorf("Error closing rows")
+			}
+		}()
+		for rows.Next() {
+			var jUUID uuid.UUID
+			if err := rows.Scan(&jUUID); err != nil {
+				return err
+			}
+			jUUIDs = append(jUUIDs, jUUID)
+		}
+		return rows.Err()
+	}(); err != nil {
+		return fmt.Errorf("scanning expired outputs: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, jUUID := range jUUIDs {
+		if err := deleteJobOutputs(ctx, jUUID); err != nil {
+			log.Sugar.Errorw("error deleting expired job outputs",
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+			continue
+		}
+		if err := db.SetStatusOutputDeleted(jUUID); err != nil {
+			continue // already logged
+		}
+		log.Sugar.Infow("deleted expired job outputs",
+			"jID", jUUID,
+		)
+	}
+	return nil
+}
+
+func deleteJobOutputs(ctx context.Context, jUUID uuid.UUID) error {
+	outputDir := path.Join("output", jUUID.String())
+	for _, o := range outputObjects {
+		p := path.Join(outputDir, o)
+		if err := storage.Delete(ctx, p); err != nil && err != storage.ErrObjectNotExist {
+			return fmt.Errorf("deleting %s from cloud storage: %v", p, err)
+		}
+	}
+	rows, err := db.GetJobCheckpoints(jUUID)
+	if err != nil {
+		return fmt.Errorf("getting job checkpoints: %v", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+	for rows.Next() {
+		var name string
+		var size int64
+		var postedAt time.Time
+		if err := rows.Scan(&name, &size, &postedAt); err != nil {
+			return fmt.Errorf("scanning job checkpoints: %v", err)
+		}
+		p := path.Join(outputDir, "checkpoints", name)
+		if err := storage.Delete(ctx, p); err != nil && err != storage.ErrObjectNotExist {
+			return fmt.Errorf("deleting %s from cloud storage: %v", p, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("scanning job checkpoints: %v", err)
+	}
+	if err := os.RemoveAll(outputDir); err != nil {
+		return fmt.Errorf("removing local output dir: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+	"time"
+)
+
+type collateralHold struct {
+	Job        uuid.UUID  `json:"job"`
+	Amount     int64      `json:"amount"`    // cents held back from the job's payout
+	Remaining  int64      `json:"remaining"` // cents not yet taken by penalties or released
+	HeldAt     time.Time  `json:"heldAt"`
+	ReleaseAt  time.Time  `json:"releaseAt"`
+	Released   int64      `json:"released,omitempty"` // cents paid out on release
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+}
+
+type collateral struct {
+	Held    int64            `json:"held"` // cents
+	Cap     int64            `json:"cap"`  // cents
+	Percent int64            `json:"percent"`
+	Holds   []collateralHold `json:"holds"`
+}
+
+// getCollateral returns the collateral held back from the miner's payouts against failure
+// penalties and when each hold is released. Pass released=true to include released holds
+var getCollateral app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	mID := r.Header.Get("X-Jwt-Claims-Subject")
+	mUUID, err := uuid.FromString(mID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing miner ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing miner ID"}
+	}
+
+	rows, err := db.GetMinerCollateral(r, mUUID, r.URL.Query().Get("released") == "true")
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	c := collateral{
+		Cap:     payments.CollateralCap,
+		Percent: payments.CollateralPercent,
+		Holds:   []collateralHold{},
+	}
+	for rows.Next() {
+		h := collateralHold{}
+		released := sql.NullInt64{}
+		releasedAt := pq.NullTime{}
+		if err = rows.Scan(&h.Job, &h.Amount, &h.Remaining, &h.HeldAt, &h.ReleaseAt, &released, &releasedAt); err != nil {
+			log.Sugar.Errorw("error scanning miner collateral",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		h.Released = released.Int64
+		if releasedAt.Valid {
+			h.ReleasedAt = &releasedAt.Time
+		}
+		c.Held += h.Remaining
+		c.Holds = append(c.Holds, h)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning miner collateral",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := json.NewEncoder(w).Encode(&c); err != nil {
+		log.Sugar.Errorw("error encoding miner collateral",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	return nil
+}
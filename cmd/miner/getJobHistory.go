@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+	"time"
+)
+
+type jobHistoryEntry struct {
+	db.JobHistoryEntry
+	Amount         int64 `json:"amount"`         // cents paid out, or to be paid out, after the platform fee and collateral
+	CollateralHeld int64 `json:"collateralHeld"` // cents held back as collateral; estimated until paid
+}
+
+type jobHistoryPage struct {
+	Jobs       []jobHistoryEntry `json:"jobs"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// getJobHistory returns a page of the jobs the miner has won, newest first, optionally
+// filtered by state, and created since / until (RFC3339)
+var getJobHistory app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	mID := r.Header.Get("X-Jwt-Claims-Subject")
+	mUUID, err := uuid.FromString(mID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing miner ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing miner ID"}
+	}
+
+	f, err := db.ParseJobHistoryFilter(r.URL.Query())
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	held, err := db.GetMinerCollateralHeld(mUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	rows, err := db.GetMinerJobHistory(mUUID, f)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	page := jobHistoryPage{
+		Jobs: []jobHistoryEntry{},
+	}
+	now := time.Now()
+	for rows.Next() {
+		jr := &db.JobHistoryRow{}
+		minerAmount, hold := sql.NullInt64{}, sql.NullInt64{}
+		percent, fixed := sql.NullFloat64{}, sql.NullInt64{}
+		if err = rows.Scan(append(jr.Dest(), &minerAmount, &hold, &percent, &fixed)...); err != nil {
+			log.Sugar.Errorw("error scanning job history",
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		e, m := jr.Entry()
+		j := jobHistoryEntry{JobHistoryEntry: e}
+		if j.State == db.JobStateFailed {
+			// failed jobs aren't paid out; the miner is charged instead
+			page.Jobs = append(page.Jobs, j)
+			continue
+		}
+		// until the job's fees are recorded, estimate the miner's share of what it's metered so far
+		if !minerAmount.Valid && m != nil {
+			amount := payments.MeteredAmount(m, now)
+			minerAmount.Int64 = amount - payments.Fee(amount, percent.Float64, fixed.Int64)
+		}
+		if hold.Valid {
+			j.CollateralHeld = hold.Int64
+		} else {
+			j.CollateralHeld = payments.CollateralHold(minerAmount.Int64, held)
+		}
+		j.Amount = minerAmount.Int64 - j.CollateralHeld
+		page.Jobs = append(page.Jobs, j)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning job history",
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if len(page.Jobs) == f.Limit {
+		last := page.Jobs[len(page.Jobs)-1]
+		page.NextCursor = db.EncodeJobHistoryCursor(last.CreatedAt, last.ID)
+	}
+
+	if err := json.NewEncoder(w).Encode(&page); err != nil {
+		log.Sugar.Errorw("error encoding miner job history",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
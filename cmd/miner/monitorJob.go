@@ -9,7 +9,7 @@ import (
 	"github.com/wminshew/emrys/pkg/check"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
-	"github.com/wminshew/emrysserver/pkg/payments"
+	"github.com/wminshew/emrysserver/pkg/notify"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -163,7 +163,6 @@ func monitorJob(jUUID uuid.UUID, notebook bool) {
 					"err", err.Error(),
 					"jID", jUUID,
 				)
-				return
 			}
 			operation = func() error {
 				// POST with empty body signifies log upload complete
@@ -200,13 +199,10 @@ func monitorJob(jUUID uuid.UUID, notebook bool) {
 					"err", err.Error(),
 					"jID", jUUID,
 				)
-				return
 			}
 
-			if err := db.SetJobFailed(jUUID); err != nil {
-				return // already logged
-			}
-			go payments.ChargeMiner(stripeChargeC, jUUID)
+			// fail the job even if its log couldn't be posted, so it doesn't stay active forever
+			_ = notify.FailJob(jUUID, db.PaymentTaskChargeMiner) // already logged
 
 			return
 		case <-activeWorkers[jUUID]:
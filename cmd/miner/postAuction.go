@@ -9,6 +9,8 @@ import (
 	"github.com/wminshew/emrysserver/pkg/app"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/notify"
+	"github.com/wminshew/emrysserver/pkg/quota"
 	"net/http"
 )
 
@@ -64,6 +66,14 @@ var postAuction app.Handler = func(w http.ResponseWriter, r *http.Request) *app.
 		return nil
 	}
 
+	uUUID, err := db.GetJobOwner(r, jUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // err already logged
+	}
+	oUUID, err := db.GetJobOrg(r, jUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // err already logged
+	}
 	reqs := &job.Specs{}
 	if err := json.NewDecoder(r.Body).Decode(reqs); err != nil {
 		return &app.Error{Code: http.StatusBadRequest, Message: "error decoding request body"}
@@ -102,6 +112,14 @@ var postAuction app.Handler = func(w http.ResponseWriter, r *http.Request) *app.
 		return &app.Error{Code: http.StatusBadRequest, Message: "invalid pcie"}
 	}
 
+	if appErr := quota.Reserve(r, uUUID, oUUID, jUUID); appErr != nil {
+		if appErr.Code != http.StatusInternalServerError {
+			// the job can't be auctioned as is, so cancel it rather than leave it image_built
+			_ = notify.CancelJob(r, jUUID) // already logged
+		}
+		return appErr
+	}
+
 	if err := db.InsertJobSpecs(r, jUUID, reqs); err != nil {
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // err already logged
 	}
@@ -65,6 +65,10 @@ var postMinerStats app.Handler = func(w http.ResponseWriter, r *http.Request) *a
 
 	for _, wStats := range minerStats.WorkerStats {
 		if !uuid.Equal(wStats.JobID, uuid.Nil) {
+			// jobs are only billed while their miner keeps reporting them
+			if err := db.SetJobHeartbeat(wStats.JobID); err != nil {
+				return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+			}
 			if ch, ok := activeWorkers[wStats.JobID]; ok {
 				ch <- struct{}{}
 			} else {
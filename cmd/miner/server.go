@@ -15,6 +15,7 @@ import (
 	"github.com/wminshew/emrysserver/pkg/auth"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
 	"github.com/wminshew/emrysserver/pkg/storage"
 	"net/http"
 	"os"
@@ -101,6 +102,22 @@ func main() {
 		B:   stripe.GetBackendWithConfig(stripe.APIBackend, stripeConfig),
 		Key: stripeSecretKey,
 	}
+	paymentsWorker := &payments.Worker{
+		ChargeC: stripeChargeC,
+	}
+	done := make(chan struct{}, 1)
+	go func() {
+		for {
+			if err := paymentsWorker.Run(); err != nil {
+				log.Sugar.Errorf("Error running payment tasks: %v\n", err)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(payments.WorkerPeriod):
+			}
+		}
+	}()
 
 	uuidRegexpMux := validate.UUIDRegexpMux()
 
@@ -115,6 +132,8 @@ func main() {
 	rMinerAuth.Use(auth.Jwt(authSecret, []string{"miner"}))
 	rMinerAuth.Handle("/connect", auth.MinerActive(connect)).Methods(http.MethodGet)
 	rMinerAuth.Handle("/stats", postMinerStats).Methods(http.MethodPost)
+	rMinerAuth.Handle("/job-history", getJobHistory).Methods(http.MethodGet)
+	rMinerAuth.Handle("/collateral", getCollateral).Methods(http.MethodGet)
 	postBidPath := fmt.Sprintf("/job/{jID:%s}/bid", uuidRegexpMux)
 	rMinerAuth.Handle(postBidPath, auth.JobActive(postBid)).Methods(http.MethodPost)
 
@@ -155,6 +174,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 	<-stop
+	close(done)
 	if err := server.Shutdown(ctx); err != nil {
 		log.Sugar.Errorf("shutting server down: %v", err)
 	}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxAdjustmentReasonLen = 1000
+)
+
+// adjustmentRequest is an admin's refund, transfer reversal or credit adjustment. Amount is in
+// cents; 0 refunds or reverses everything left. ID optionally names a refund or reversal, so
+// retrying it after an error finishes it rather than making another
+type adjustmentRequest struct {
+	ID     uuid.UUID `json:"id"`
+	Amount int64     `json:"amount"`
+	Method string    `json:"method"`
+	Job    uuid.UUID `json:"job"`
+	Reason string    `json:"reason"`
+}
+
+type adjustment struct {
+	ID        uuid.UUID                `json:"id"`
+	Kind      db.PaymentAdjustmentKind `json:"kind"`
+	Job       *uuid.UUID               `json:"job,omitempty"`
+	Account   uuid.UUID                `json:"account"`
+	Amount    int64                    `json:"amount"`
+	Method    string                   `json:"method"`
+	StripeID  string                   `json:"stripeId,omitempty"`
+	Reason    string                   `json:"reason"`
+	Admin     uuid.UUID                `json:"admin"`
+	CreatedAt *time.Time               `json:"createdAt,omitempty"`
+	Pending   bool                     `json:"pending,omitempty"`
+}
+
+// parseAdjustmentRequest returns the requesting admin, the job in the path (uuid.Nil if none) and
+// the decoded adjustment request
+func parseAdjustmentRequest(r *http.Request) (uuid.UUID, uuid.UUID, *adjustmentRequest, *app.Error) {
+	adminUUID, err := uuid.FromString(r.Header.Get("X-Jwt-Claims-Subject"))
+	if err != nil {
+		log.Sugar.Errorw("error parsing admin ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return uuid.Nil, uuid.Nil, nil, &app.Error{Code: http.StatusBadRequest, Message: "error parsing admin ID"}
+	}
+
+	jUUID := uuid.Nil
+	if jID, ok := mux.Vars(r)["jID"]; ok {
+		if jUUID, err = uuid.FromString(jID); err != nil {
+			return uuid.Nil, uuid.Nil, nil, &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+		}
+	}
+
+	req := &adjustmentRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Sugar.Infow("error decoding adjustment request",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return uuid.Nil, uuid.Nil, nil, &app.Error{Code: http.StatusBadRequest, Message: "error decoding request body"}
+	}
+	if uuid.Equal(req.ID, uuid.Nil) {
+		req.ID = uuid.NewV4()
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return uuid.Nil, uuid.Nil, nil, &app.Error{Code: http.StatusBadRequest, Message: "a reason is required"}
+	} else if utf8.RuneCountInString(req.Reason) > maxAdjustmentReasonLen {
+		return uuid.Nil, uuid.Nil, nil, &app.Error{Code: http.StatusBadRequest, Message: "reason is too long"}
+	} else if req.Amount < 0 && !uuid.Equal(jUUID, uuid.Nil) {
+		return uuid.Nil, uuid.Nil, nil, &app.Error{Code: http.StatusBadRequest, Message: "amount can't be negative"}
+	}
+	return adminUUID, jUUID, req, nil
+}
+
+// adjustmentError maps errors from the payments adjustment functions to responses
+func adjustmentError(err error) *app.Error {
+	switch err {
+	case payments.ErrJobNotCharged, payments.ErrJobNotPaid, db.ErrPaymentAdjustmentIDInUse:
+		return &app.Error{Code: http.StatusConflict, Message: err.Error()}
+	case payments.ErrAdjustmentTooLarge, payments.ErrOrgCreditRefund:
+		return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+}
+
+// writeAdjustment writes the adjustment adj an admin just made
+func writeAdjustment(w http.ResponseWriter, r *http.Request, adj *db.PaymentAdjustment) *app.Error {
+	a := adjustment{
+		ID:       adj.UUID,
+		Kind:     adj.Kind,
+		Account:  adj.Account,
+		Amount:   adj.Amount,
+		Method:   adj.Method,
+		StripeID: adj.StripeID,
+		Reason:   adj.Reason,
+		Admin:    adj.Admin,
+	}
+	if !uuid.Equal(adj.Job, uuid.Nil) {
+		a.Job = &adj.Job
+	}
+	log.Sugar.Infow("payment adjustment",
+		"method", r.Method,
+		"url", r.URL,
+		"adjID", a.ID,
+		"kind", a.Kind,
+		"jID", adj.Job,
+		"aID", a.Account,
+		"amount", a.Amount,
+		"admin", a.Admin,
+	)
+
+	if err := json.NewEncoder(w).Encode(&a); err != nil {
+		log.Sugar.Errorw("error encoding adjustment",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	return nil
+}
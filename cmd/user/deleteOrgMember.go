@@ -0,0 +1,46 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"net/http"
+)
+
+// deleteOrgMember removes a member from the organization. Any member may leave; admins
+// may remove others and only owners may remove owners
+var deleteOrgMember app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	oUUID, uUUID, callerRole, appErr := orgAccess(r, db.OrgRoleViewer)
+	if appErr != nil {
+		return appErr
+	}
+	aUUID, err := uuid.FromString(mux.Vars(r)["aID"])
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing member ID"}
+	}
+
+	if !uuid.Equal(aUUID, uUUID) {
+		current, err := db.GetOrgRole(r, oUUID, aUUID)
+		if err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+		} else if !callerRole.AtLeast(db.OrgRoleAdmin) {
+			return &app.Error{Code: http.StatusForbidden, Message: "requires org role admin"}
+		} else if current == "" {
+			return &app.Error{Code: http.StatusNotFound, Message: "member not found"}
+		} else if current == db.OrgRoleOwner && callerRole != db.OrgRoleOwner {
+			return &app.Error{Code: http.StatusForbidden, Message: "only owners may remove owners"}
+		}
+	}
+
+	if err := db.DeleteOrgMember(r, oUUID, aUUID); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "member not found"}
+	} else if err == db.ErrOrgLastOwner {
+		return &app.Error{Code: http.StatusConflict, Message: err.Error()}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// deleteProject removes the user's project along with its cached data set and images
+var deleteProject app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	vars := mux.Vars(r)
+	project := vars["project"]
+	// no new jobs can be posted to the project while its data and images are deleted
+	if err := db.SetProjectBusy(r, uUUID, project, db.ProjectDeleting); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "project not found"}
+	} else if err == db.ErrProjectActive {
+		return &app.Error{Code: http.StatusConflict, Message: "project has active jobs"}
+	} else if err == db.ErrProjectBusy {
+		return &app.Error{Code: http.StatusConflict, Message: "project is being renamed"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	u := url.URL{
+		Scheme: "http",
+		Host:   "data-svc:8080",
+		Path:   path.Join("project", project),
+	}
+	if err := forwardRequest(r, http.MethodDelete, u, nil); err != nil {
+		log.Sugar.Errorw("error deleting project data--aborting",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"project", project,
+		)
+		_ = db.ClearProjectBusy(r, uUUID, project) // already logged
+		return &app.Error{Code: http.StatusInternalServerError, Message: "error deleting project data"}
+	}
+
+	u = url.URL{
+		Scheme: "http",
+		Host:   "image-svc:8080",
+		Path:   path.Join("image", project),
+	}
+	if err := forwardRequest(r, http.MethodDelete, u, nil); err != nil {
+		log.Sugar.Errorw("error deleting project images--aborting",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"project", project,
+		)
+		_ = db.ClearProjectBusy(r, uUUID, project) // already logged
+		return &app.Error{Code: http.StatusInternalServerError, Message: "error deleting project images"}
+	}
+
+	if err := db.DeleteProject(r, uUUID, project); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "project not found"}
+	} else if err == db.ErrProjectActive {
+		return &app.Error{Code: http.StatusConflict, Message: "project has active jobs"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// deleteWebhook removes one of the account's webhooks
+var deleteWebhook app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	vars := mux.Vars(r)
+	whUUID, err := uuid.FromString(vars["whID"])
+	if err != nil {
+		log.Sugar.Errorw("error parsing webhook ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing webhook ID"}
+	}
+
+	if err := db.DeleteWebhook(r, aUUID, whUUID); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "webhook not found"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	return nil
+}
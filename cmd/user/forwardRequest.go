@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/cenkalti/backoff"
+	"github.com/wminshew/emrys/pkg/check"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// serviceError is a non-retryable response from another service
+type serviceError struct {
+	code    int
+	message string
+}
+
+func (e *serviceError) Error() string {
+	return fmt.Sprintf("server: %v", e.message)
+}
+
+// forwardRequest makes a request to another service on the user's behalf, retrying temporary
+// errors. Other error responses are returned as a *serviceError
+func forwardRequest(r *http.Request, method string, u url.URL, body []byte) error {
+	ctx := r.Context()
+	client := &http.Client{}
+	operation := func() error {
+		req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Authorization", r.Header.Get("Authorization"))
+		req = req.WithContext(ctx)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer check.Err(resp.Body.Close)
+
+		if resp.StatusCode == http.StatusBadGateway {
+			return fmt.Errorf("server: temporary error")
+		} else if resp.StatusCode >= 300 {
+			b, _ := ioutil.ReadAll(resp.Body)
+			return backoff.Permanent(&serviceError{code: resp.StatusCode, message: strings.TrimSpace(string(b))})
+		}
+
+		return nil
+	}
+	return backoff.RetryNotify(operation,
+		backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries), ctx),
+		func(err error, t time.Duration) {
+			log.Sugar.Errorw("error forwarding request, retrying",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"host", u.Host,
+			)
+		})
+}
+
+// forwardedAppError passes a service's client error response through to the user and
+// reports anything else with message
+func forwardedAppError(err error, message string) *app.Error {
+	if sErr, ok := err.(*serviceError); ok && sErr.code < http.StatusInternalServerError {
+		return &app.Error{Code: sErr.code, Message: sErr.message}
+	}
+	return &app.Error{Code: http.StatusInternalServerError, Message: message}
+}
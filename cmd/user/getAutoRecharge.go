@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// autoRecharge is the credit pack an account buys automatically whenever a job's charge leaves
+// its credit below Threshold (cents). An empty Pack means auto-recharge is off
+type autoRecharge struct {
+	Pack      string `json:"pack"`
+	Threshold int64  `json:"threshold"`
+}
+
+// getAutoRecharge returns the account's auto-recharge settings
+var getAutoRecharge app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	ar := autoRecharge{}
+	if ar.Pack, ar.Threshold, err = db.GetAutoRecharge(r, aUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	if err := json.NewEncoder(w).Encode(&ar); err != nil {
+		log.Sugar.Errorw("error encoding auto-recharge",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	return nil
+}
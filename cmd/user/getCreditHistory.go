@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultCreditHistoryLimit = 50
+	maxCreditHistoryLimit     = 200
+)
+
+type creditHistoryEntry struct {
+	ID        int64         `json:"id"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Kind      db.LedgerKind `json:"kind"`
+	Job       *uuid.UUID    `json:"job,omitempty"`
+	Amount    int64         `json:"amount"` // cents, positive when credit was added
+}
+
+type creditHistoryPage struct {
+	Entries []creditHistoryEntry `json:"entries"`
+	Before  int64                `json:"before,omitempty"`
+}
+
+// getCreditHistory returns a page of the account's credit transactions (grants, purchases,
+// job charges, refunds and adjustments), newest first. Pass a page's before to get the next
+var getCreditHistory app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	q := r.URL.Query()
+	var before int64
+	if b := q.Get("before"); b != "" {
+		if before, err = strconv.ParseInt(b, 10, 64); err != nil || before < 1 {
+			return &app.Error{Code: http.StatusBadRequest, Message: "invalid before"}
+		}
+	}
+	limit := defaultCreditHistoryLimit
+	if l := q.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > maxCreditHistoryLimit {
+			return &app.Error{Code: http.StatusBadRequest,
+				Message: "limit must be between 1 and " + strconv.Itoa(maxCreditHistoryLimit)}
+		}
+	}
+
+	rows, err := db.GetAccountCreditHistory(r, aUUID, before, limit)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	page := creditHistoryPage{
+		Entries: []creditHistoryEntry{},
+	}
+	for rows.Next() {
+		e := creditHistoryEntry{}
+		job := uuid.NullUUID{}
+		if err = rows.Scan(&e.ID, &e.CreatedAt, &e.Kind, &job, &e.Amount); err != nil {
+			log.Sugar.Errorw("error scanning credit history",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		if job.Valid {
+			e.Job = &job.UUID
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning credit history",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if len(page.Entries) == limit {
+		page.Before = page.Entries[len(page.Entries)-1].ID
+	}
+
+	if err := json.NewEncoder(w).Encode(&page); err != nil {
+		log.Sugar.Errorw("error encoding credit history",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	return nil
+}
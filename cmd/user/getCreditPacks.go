@@ -0,0 +1,22 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+)
+
+// getCreditPacks returns the credit packs for sale
+var getCreditPacks app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	if err := json.NewEncoder(w).Encode(payments.CreditPacks); err != nil {
+		log.Sugar.Errorw("error encoding credit packs",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// getJobAdjustments returns the audit trail of refunds, reversals and credit adjustments
+// made for a job, oldest first
+var getJobAdjustments app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	jUUID, err := uuid.FromString(mux.Vars(r)["jID"])
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+
+	rows, err := db.GetJobAdjustments(r, jUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	adjs := []adjustment{}
+	for rows.Next() {
+		a := adjustment{Job: &jUUID}
+		createdAt := time.Time{}
+		if err = rows.Scan(&a.ID, &a.Kind, &a.Account, &a.Amount, &a.Method, &a.StripeID,
+			&a.Reason, &a.Admin, &createdAt, &a.Pending); err != nil {
+			log.Sugar.Errorw("error scanning job adjustments",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		a.CreatedAt = &createdAt
+		adjs = append(adjs, a)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning job adjustments",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := json.NewEncoder(w).Encode(adjs); err != nil {
+		log.Sugar.Errorw("error encoding job adjustments",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
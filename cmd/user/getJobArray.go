@@ -0,0 +1,119 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+type jobArrayStatus struct {
+	ID         uuid.UUID           `json:"id"`
+	SourceJob  uuid.UUID           `json:"sourceJob"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	CanceledAt *time.Time          `json:"canceledAt,omitempty"`
+	States     map[db.JobState]int `json:"states"`
+	Jobs       []jobArrayMember    `json:"jobs"`
+}
+
+// getJobArray returns the aggregate status of one of the user's job arrays
+var getJobArray app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	status, appErr := jobArrayStatusOf(r)
+	if appErr != nil {
+		return appErr
+	}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Sugar.Errorw("error encoding job array status",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
+
+// jobArrayStatusOf looks up the user's job array arrID in project
+func jobArrayStatusOf(r *http.Request) (*jobArrayStatus, *app.Error) {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return nil, &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+	vars := mux.Vars(r)
+	arrUUID, err := uuid.FromString(vars["arrID"])
+	if err != nil {
+		log.Sugar.Errorw("error parsing job array ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return nil, &app.Error{Code: http.StatusBadRequest, Message: "error parsing job array ID"}
+	}
+
+	status := &jobArrayStatus{
+		ID:     arrUUID,
+		States: map[db.JobState]int{},
+		Jobs:   []jobArrayMember{},
+	}
+	var canceledAt time.Time
+	status.SourceJob, status.CreatedAt, canceledAt, err = db.GetJobArray(r, uUUID, arrUUID)
+	if err == sql.ErrNoRows {
+		return nil, &app.Error{Code: http.StatusNotFound, Message: "job array not found"}
+	} else if err != nil {
+		return nil, &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	if !canceledAt.IsZero() {
+		status.CanceledAt = &canceledAt
+	}
+	if _, project, err := db.GetJobOwnerAndProject(r, status.SourceJob); err != nil {
+		return nil, &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if project != vars["project"] {
+		return nil, &app.Error{Code: http.StatusNotFound, Message: "job array not found"}
+	}
+
+	rows, err := db.GetJobArrayJobs(arrUUID)
+	if err != nil {
+		return nil, &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		j := jobArrayMember{}
+		args := pq.StringArray{}
+		if err = rows.Scan(&j.ID, &j.Index, &args, &j.State); err != nil {
+			log.Sugar.Errorw("error scanning job array jobs",
+				"err", err.Error(),
+			)
+			return nil, &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		j.Args = args
+		status.States[j.State]++
+		status.Jobs = append(status.Jobs, j)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning job array jobs",
+			"err", err.Error(),
+		)
+		return nil, &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return status, nil
+}
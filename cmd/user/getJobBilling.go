@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"math"
+	"net/http"
+	"time"
+)
+
+// jobBilling breaks a job's bill down into segments. Amounts are in cents, accrued so far
+// if the job is still running
+type jobBilling struct {
+	Rate          float64            `json:"rate"`
+	Segments      []payments.Segment `json:"segments"`
+	BilledSeconds int64              `json:"billedSeconds"`
+	Amount        int64              `json:"amount"`
+}
+
+// getJobBilling returns the segments of the job's life and which of them are billed
+var getJobBilling app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+
+	m, err := db.GetJobMetering(jUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	b := jobBilling{
+		Rate:     m.Rate,
+		Segments: payments.Segments(m, time.Now()),
+	}
+	for _, s := range b.Segments {
+		if s.Billed {
+			b.BilledSeconds += int64(math.Ceil(s.End.Sub(s.Start).Seconds()))
+			b.Amount += s.Amount
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(&b); err != nil {
+		log.Sugar.Errorw("error encoding job billing",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
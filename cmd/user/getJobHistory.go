@@ -1,15 +1,35 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"github.com/lib/pq"
 	"github.com/satori/go.uuid"
 	"github.com/wminshew/emrysserver/pkg/app"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
 	"net/http"
+	"time"
 )
 
-// getJobHistory returns the accounts job history
+type jobHistoryEntry struct {
+	db.JobHistoryEntry
+	Project  string            `json:"project"`
+	Cost     int64             `json:"cost"` // cents, accrued so far if still running
+	Name     string            `json:"name,omitempty"`
+	Tags     []string          `json:"tags"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type jobHistoryPage struct {
+	Jobs       []jobHistoryEntry `json:"jobs"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// getJobHistory returns a page of the account's job history, newest first, optionally
+// filtered by project, state, created since / until (RFC3339), name, tags (repeated tag),
+// metadata (repeated meta=key=value) and a search query q
 var getJobHistory app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
 	aID := r.Header.Get("X-Jwt-Claims-Subject")
 	aUUID, err := uuid.FromString(aID)
@@ -22,12 +42,12 @@ var getJobHistory app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
 	}
 
-	// TODO: add job type, etc
-	// jobHistory := make(map[uuid.UUID]job)
-	jobHistory := []uuid.UUID{}
+	f, err := parseJobHistoryFilter(r)
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+	}
 
-	// TODO: break into user / miner job histories?
-	rows, err := db.GetAccountJobHistory(aUUID)
+	rows, err := db.GetAccountJobHistory(aUUID, f)
 	if err != nil {
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
 	}
@@ -37,15 +57,37 @@ var getJobHistory app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 		}
 	}()
 
+	page := jobHistoryPage{
+		Jobs: []jobHistoryEntry{},
+	}
+	now := time.Now()
 	for rows.Next() {
-		var aUUID uuid.UUID
-		if err = rows.Scan(&aUUID); err != nil {
+		jr := &db.JobHistoryRow{}
+		j := jobHistoryEntry{}
+		name := sql.NullString{}
+		tags := pq.StringArray{}
+		var metadata []byte
+		if err = rows.Scan(append(jr.Dest(), &j.Project, &name, &tags, &metadata)...); err != nil {
 			log.Sugar.Errorw("error scanning job history",
 				"err", err.Error(),
 			)
 			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 		}
-		jobHistory = append(jobHistory, aUUID)
+		e, m := jr.Entry()
+		j.JobHistoryEntry = e
+		if err = json.Unmarshal(metadata, &j.Metadata); err != nil {
+			log.Sugar.Errorw("error decoding job metadata",
+				"err", err.Error(),
+				"jID", j.ID,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		j.Name = name.String
+		j.Tags = tags
+		if m != nil {
+			j.Cost = payments.MeteredAmount(m, now)
+		}
+		page.Jobs = append(page.Jobs, j)
 	}
 	if err = rows.Err(); err != nil {
 		log.Sugar.Errorw("error scanning job history",
@@ -54,7 +96,12 @@ var getJobHistory app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 	}
 
-	if err := json.NewEncoder(w).Encode(&jobHistory); err != nil {
+	if len(page.Jobs) == f.Limit {
+		last := page.Jobs[len(page.Jobs)-1]
+		page.NextCursor = db.EncodeJobHistoryCursor(last.CreatedAt, last.ID)
+	}
+
+	if err := json.NewEncoder(w).Encode(&page); err != nil {
 		log.Sugar.Errorw("error encoding account job history",
 			"method", r.Method,
 			"url", r.URL,
@@ -65,3 +112,24 @@ var getJobHistory app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 
 	return nil
 }
+
+// parseJobHistoryFilter parses project, name, tag, meta and q from r's query, along with the
+// filters shared with miners
+func parseJobHistoryFilter(r *http.Request) (*db.JobHistoryFilter, error) {
+	q := r.URL.Query()
+	f, err := db.ParseJobHistoryFilter(q)
+	if err != nil {
+		return nil, err
+	}
+	f.Project = q.Get("project")
+	f.Name = q.Get("name")
+	f.Tags = q["tag"]
+	f.Query = q.Get("q")
+	if err := validateJobTags(f.Tags); err != nil {
+		return nil, err
+	}
+	if f.Metadata, err = parseJobMetadataPairs(q["meta"]); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
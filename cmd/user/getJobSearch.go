@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/wminshew/emrysserver/pkg/app"
+	"net/http"
+	"strings"
+)
+
+// getJobSearch returns a page of the account's jobs whose name, a tag or a metadata value
+// matches the query q. It accepts the same filters and paging as getJobHistory
+var getJobSearch app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	if strings.TrimSpace(r.URL.Query().Get("q")) == "" {
+		return &app.Error{Code: http.StatusBadRequest, Message: "search query q is required"}
+	}
+	return getJobHistory(w, r)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// getNotifyEmail returns whether the account receives job notification emails
+var getNotifyEmail app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	notify, err := db.GetAccountNotifyEmail(r, aUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	if err := json.NewEncoder(w).Encode(&notify); err != nil {
+		log.Sugar.Errorw("error encoding account notify email",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+type orgMember struct {
+	ID       uuid.UUID  `json:"id"`
+	Email    string     `json:"email"`
+	Role     db.OrgRole `json:"role"`
+	JoinedAt time.Time  `json:"joinedAt"`
+}
+
+// getOrgMembers returns the organization's members
+var getOrgMembers app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	oUUID, _, _, appErr := orgAccess(r, db.OrgRoleViewer)
+	if appErr != nil {
+		return appErr
+	}
+
+	members := []orgMember{}
+	rows, err := db.GetOrgMembers(oUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		m := orgMember{}
+		if err = rows.Scan(&m.ID, &m.Email, &m.Role, &m.JoinedAt); err != nil {
+			log.Sugar.Errorw("error scanning org members",
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		members = append(members, m)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning org members",
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := json.NewEncoder(w).Encode(&members); err != nil {
+		log.Sugar.Errorw("error encoding org members",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// getOrgProjects returns the organization's projects
+var getOrgProjects app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	oUUID, _, _, appErr := orgAccess(r, db.OrgRoleViewer)
+	if appErr != nil {
+		return appErr
+	}
+
+	projects := []project{}
+	rows, err := db.GetOrgProjects(oUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		p := project{}
+		lastJobAt := pq.NullTime{}
+		if err = rows.Scan(&p.Name, &p.Jobs, &p.ActiveJobs, &lastJobAt); err != nil {
+			log.Sugar.Errorw("error scanning org projects",
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		if lastJobAt.Valid {
+			p.LastJobAt = &lastJobAt.Time
+		}
+		projects = append(projects, p)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning org projects",
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := json.NewEncoder(w).Encode(&projects); err != nil {
+		log.Sugar.Errorw("error encoding org projects",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// getOrgs returns the organizations the user belongs to and its role in each
+var getOrgs app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	orgs := []org{}
+	rows, err := db.GetAccountOrgs(uUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		o := org{}
+		if err = rows.Scan(&o.ID, &o.Name, &o.Role); err != nil {
+			log.Sugar.Errorw("error scanning account orgs",
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		orgs = append(orgs, o)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning account orgs",
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := json.NewEncoder(w).Encode(&orgs); err != nil {
+		log.Sugar.Errorw("error encoding account orgs",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
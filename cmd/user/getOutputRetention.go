@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+type outputRetention struct {
+	Days     int64 `json:"days"`
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// getOutputRetention returns the account's output retention policy (0 is unlimited)
+var getOutputRetention app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	days, maxBytes, err := db.GetAccountOutputRetention(r, aUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	if err := json.NewEncoder(w).Encode(&outputRetention{
+		Days:     days,
+		MaxBytes: maxBytes,
+	}); err != nil {
+		log.Sugar.Errorw("error encoding account output retention",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+type project struct {
+	Name       string     `json:"name"`
+	Jobs       int64      `json:"jobs"`
+	ActiveJobs int64      `json:"activeJobs"`
+	LastJobAt  *time.Time `json:"lastJobAt,omitempty"`
+}
+
+// getProjects returns the user's projects
+var getProjects app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	projects := []project{}
+	rows, err := db.GetAccountProjects(uUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		p := project{}
+		lastJobAt := pq.NullTime{}
+		if err = rows.Scan(&p.Name, &p.Jobs, &p.ActiveJobs, &lastJobAt); err != nil {
+			log.Sugar.Errorw("error scanning account projects",
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		if lastJobAt.Valid {
+			p.LastJobAt = &lastJobAt.Time
+		}
+		projects = append(projects, p)
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning account projects",
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if err := json.NewEncoder(w).Encode(&projects); err != nil {
+		log.Sugar.Errorw("error encoding account projects",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// usage reports usage against limits. Omitted limits are unlimited
+type usage struct {
+	PeriodStart        time.Time `json:"periodStart"`
+	ConcurrentJobs     int64     `json:"concurrentJobs"`
+	MaxConcurrentJobs  int64     `json:"maxConcurrentJobs,omitempty"`
+	MonthlySpend       int64     `json:"monthlySpend"`
+	MaxMonthlySpend    int64     `json:"maxMonthlySpend,omitempty"`
+	MonthlyGPUHours    float64   `json:"monthlyGpuHours"`
+	MaxMonthlyGPUHours float64   `json:"maxMonthlyGpuHours,omitempty"`
+}
+
+// getUsage returns the user's personal usage against its limits
+var getUsage app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	return writeUsage(w, r, uUUID, uuid.Nil)
+}
+
+// getOrgUsage returns the organization's usage against its limits
+var getOrgUsage app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	oUUID, uUUID, _, appErr := orgAccess(r, db.OrgRoleViewer)
+	if appErr != nil {
+		return appErr
+	}
+
+	return writeUsage(w, r, uUUID, oUUID)
+}
+
+// writeUsage writes the usage of organization oUUID or, if it's uuid.Nil, of account aUUID
+func writeUsage(w http.ResponseWriter, r *http.Request, aUUID, oUUID uuid.UUID) *app.Error {
+	q, err := db.GetQuota(r, aUUID, oUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	u := usage{
+		PeriodStart:        q.PeriodStart,
+		ConcurrentJobs:     q.ConcurrentJobs,
+		MaxConcurrentJobs:  q.MaxConcurrentJobs,
+		MonthlySpend:       q.MonthlySpend,
+		MaxMonthlySpend:    q.MaxMonthlySpend,
+		MonthlyGPUHours:    q.MonthlyGPUHours,
+		MaxMonthlyGPUHours: q.MaxMonthlyGPUHours,
+	}
+	if err := json.NewEncoder(w).Encode(&u); err != nil {
+		log.Sugar.Errorw("error encoding usage",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
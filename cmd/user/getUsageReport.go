@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	reportMonthLayout = "2006-01"
+)
+
+type usageReportRow struct {
+	Project        string  `json:"project"`
+	Account        string  `json:"account"`
+	GPU            string  `json:"gpu"`
+	Jobs           int64   `json:"jobs"`
+	GPUHours       float64 `json:"gpuHours"`
+	CreditApplied  int64   `json:"creditApplied"`
+	AmountInvoiced int64   `json:"amountInvoiced"`
+}
+
+// usageReport breaks a month's jobs down by project, creator and gpu. Amounts are in cents
+type usageReport struct {
+	Month  string           `json:"month"`
+	Rows   []usageReportRow `json:"rows"`
+	Totals usageReportRow   `json:"totals"`
+}
+
+// getUsageReport returns the user's personal usage report for month (YYYY-MM, default
+// this month) as json, or csv if format=csv
+var getUsageReport app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	return writeUsageReport(w, r, uUUID, uuid.Nil)
+}
+
+// getOrgUsageReport returns the organization's usage report, like getUsageReport
+var getOrgUsageReport app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	oUUID, uUUID, _, appErr := orgAccess(r, db.OrgRoleAdmin)
+	if appErr != nil {
+		return appErr
+	}
+
+	return writeUsageReport(w, r, uUUID, oUUID)
+}
+
+// writeUsageReport writes the usage report of organization oUUID or, if it's uuid.Nil, of
+// account aUUID's personal projects. Jobs are reported in the month they ended
+func writeUsageReport(w http.ResponseWriter, r *http.Request, aUUID, oUUID uuid.UUID) *app.Error {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		return &app.Error{Code: http.StatusBadRequest, Message: "format must be json or csv"}
+	}
+	t := time.Now().UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if month := q.Get("month"); month != "" {
+		var err error
+		if start, err = time.Parse(reportMonthLayout, month); err != nil {
+			return &app.Error{Code: http.StatusBadRequest, Message: "month must be formatted YYYY-MM"}
+		}
+	}
+	end := start.AddDate(0, 1, 0)
+
+	report := usageReport{
+		Month: start.Format(reportMonthLayout),
+		Rows:  []usageReportRow{},
+	}
+	rows, err := db.GetUsageReport(aUUID, oUUID, start, end)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		row := usageReportRow{}
+		var gpuSeconds float64
+		if err = rows.Scan(&row.Project, &row.Account, &row.GPU, &row.Jobs, &gpuSeconds,
+			&row.CreditApplied, &row.AmountInvoiced); err != nil {
+			log.Sugar.Errorw("error scanning usage report",
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		row.GPUHours = gpuSeconds / 3600
+		report.Rows = append(report.Rows, row)
+		report.Totals.Jobs += row.Jobs
+		report.Totals.GPUHours += row.GPUHours
+		report.Totals.CreditApplied += row.CreditApplied
+		report.Totals.AmountInvoiced += row.AmountInvoiced
+	}
+	if err = rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning usage report",
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"emrys-usage-%s.csv\"", report.Month))
+		cw := csv.NewWriter(w)
+		records := [][]string{{"month", "project", "account", "gpu", "jobs", "gpu_hours", "credit_applied_cents", "amount_invoiced_cents"}}
+		for _, row := range report.Rows {
+			records = append(records, []string{
+				report.Month,
+				row.Project,
+				row.Account,
+				row.GPU,
+				strconv.FormatInt(row.Jobs, 10),
+				strconv.FormatFloat(row.GPUHours, 'f', 4, 64),
+				strconv.FormatInt(row.CreditApplied, 10),
+				strconv.FormatInt(row.AmountInvoiced, 10),
+			})
+		}
+		if err := cw.WriteAll(records); err != nil {
+			log.Sugar.Errorw("error writing usage report csv",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		return nil
+	}
+
+	if err := json.NewEncoder(w).Encode(&report); err != nil {
+		log.Sugar.Errorw("error encoding usage report",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxJobNameLen          = 128
+	maxJobTags             = 32
+	maxJobMetadata         = 64
+	maxJobMetadataValueLen = 512
+)
+
+var (
+	jobTagRegexp         = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,64}$`)
+	jobMetadataKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+)
+
+// parseJobLabels parses the job's name, tags (repeated tag) and metadata (repeated meta=key=value)
+// from q
+func parseJobLabels(q url.Values) (*db.JobLabels, error) {
+	labels := &db.JobLabels{
+		Name: q.Get("name"),
+		Tags: q["tag"],
+	}
+	if err := validateJobName(labels.Name); err != nil {
+		return nil, err
+	}
+	if err := validateJobTags(labels.Tags); err != nil {
+		return nil, err
+	}
+	var err error
+	if labels.Metadata, err = parseJobMetadataPairs(q["meta"]); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func validateJobName(name string) error {
+	if utf8.RuneCountInString(name) > maxJobNameLen {
+		return fmt.Errorf("name must be at most %d characters", maxJobNameLen)
+	}
+	return nil
+}
+
+func validateJobTags(tags []string) error {
+	if len(tags) > maxJobTags {
+		return fmt.Errorf("at most %d tags are allowed", maxJobTags)
+	}
+	for _, tag := range tags {
+		if !jobTagRegexp.MatchString(tag) {
+			return fmt.Errorf("invalid tag: %s", tag)
+		}
+	}
+	return nil
+}
+
+func validateJobMetadata(key, value string) error {
+	if !jobMetadataKeyRegexp.MatchString(key) {
+		return fmt.Errorf("invalid metadata key: %s", key)
+	} else if utf8.RuneCountInString(value) > maxJobMetadataValueLen {
+		return fmt.Errorf("metadata value for %s must be at most %d characters", key, maxJobMetadataValueLen)
+	}
+	return nil
+}
+
+// parseJobMetadataPairs parses and validates key=value pairs
+func parseJobMetadataPairs(pairs []string) (map[string]string, error) {
+	if len(pairs) > maxJobMetadata {
+		return nil, fmt.Errorf("at most %d metadata keys are allowed", maxJobMetadata)
+	}
+	metadata := map[string]string{}
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("metadata must be key=value: %s", pair)
+		}
+		if err := validateJobMetadata(kv[0], kv[1]); err != nil {
+			return nil, err
+		}
+		metadata[kv[0]] = kv[1]
+	}
+	return metadata, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"unicode/utf8"
+)
+
+const (
+	maxOrgNameLen = 64
+)
+
+type org struct {
+	ID   uuid.UUID  `json:"id"`
+	Name string     `json:"name"`
+	Role db.OrgRole `json:"role"`
+}
+
+// orgAccess returns the requested organization, the requesting user and its role, or an
+// error unless the role is at least minRole. Non-members are told the org doesn't exist
+func orgAccess(r *http.Request, minRole db.OrgRole) (uuid.UUID, uuid.UUID, db.OrgRole, *app.Error) {
+	oUUID, err := uuid.FromString(mux.Vars(r)["orgID"])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", &app.Error{Code: http.StatusBadRequest, Message: "error parsing org ID"}
+	}
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return uuid.Nil, uuid.Nil, "", &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	role, err := db.GetOrgRole(r, oUUID, uUUID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if !role.AtLeast(db.OrgRoleViewer) {
+		return uuid.Nil, uuid.Nil, "", &app.Error{Code: http.StatusNotFound, Message: "org not found"}
+	} else if !role.AtLeast(minRole) {
+		return uuid.Nil, uuid.Nil, "", &app.Error{Code: http.StatusForbidden, Message: "requires org role " + string(minRole)}
+	}
+	return oUUID, uUUID, role, nil
+}
+
+// parseJobOrg returns the organization named by r's org query, or uuid.Nil if there is
+// none, and checks user uUUID may run jobs in it
+func parseJobOrg(r *http.Request, uUUID uuid.UUID) (uuid.UUID, *app.Error) {
+	orgID := r.URL.Query().Get("org")
+	if orgID == "" {
+		return uuid.Nil, nil
+	}
+	oUUID, err := uuid.FromString(orgID)
+	if err != nil {
+		return uuid.Nil, &app.Error{Code: http.StatusBadRequest, Message: "error parsing org ID"}
+	}
+	return oUUID, checkOrgJobRole(r, oUUID, uUUID)
+}
+
+// checkOrgJobRole returns an error unless user uUUID may run jobs in organization oUUID.
+// Personal jobs (uuid.Nil) are always allowed
+func checkOrgJobRole(r *http.Request, oUUID, uUUID uuid.UUID) *app.Error {
+	if uuid.Equal(oUUID, uuid.Nil) {
+		return nil
+	}
+	role, err := db.GetOrgRole(r, oUUID, uUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if !role.AtLeast(db.OrgRoleViewer) {
+		return &app.Error{Code: http.StatusNotFound, Message: "org not found"}
+	} else if !role.AtLeast(db.OrgRoleMember) {
+		return &app.Error{Code: http.StatusForbidden, Message: "org viewers can't run jobs"}
+	}
+	return nil
+}
+
+// validateOrgName returns an error unless name is a usable organization name
+func validateOrgName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	} else if utf8.RuneCountInString(name) > maxOrgNameLen {
+		return fmt.Errorf("name must be at most %d characters", maxOrgNameLen)
+	}
+	return nil
+}
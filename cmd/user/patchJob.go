@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// jobLabelsPatch edits a job's labels. Omitted fields are left unchanged; an empty name clears
+// it and a null metadata value deletes that key
+type jobLabelsPatch struct {
+	Name     *string            `json:"name"`
+	Tags     *[]string          `json:"tags"`
+	Metadata map[string]*string `json:"metadata"`
+}
+
+// patchJob edits the name, tags and metadata of one of the user's jobs
+var patchJob app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	jID := vars["jID"]
+	jUUID, err := uuid.FromString(jID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+
+	p := jobLabelsPatch{}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		log.Sugar.Infow("error decoding job labels patch",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error decoding request body"}
+	}
+
+	if p.Name != nil {
+		if err := validateJobName(*p.Name); err != nil {
+			return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+	}
+	if p.Tags != nil {
+		if err := validateJobTags(*p.Tags); err != nil {
+			return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+	}
+	if len(p.Metadata) > maxJobMetadata {
+		return &app.Error{Code: http.StatusBadRequest,
+			Message: fmt.Sprintf("at most %d metadata keys are allowed", maxJobMetadata)}
+	}
+	setMetadata := map[string]string{}
+	deleteMetadata := []string{}
+	for k, v := range p.Metadata {
+		if v == nil {
+			deleteMetadata = append(deleteMetadata, k)
+			continue
+		}
+		if err := validateJobMetadata(k, *v); err != nil {
+			return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+		setMetadata[k] = *v
+	}
+
+	if err := db.SetJobLabels(r, jUUID, p.Name, p.Tags, setMetadata, deleteMetadata,
+		maxJobMetadata); err == db.ErrJobMetadataFull {
+		return &app.Error{Code: http.StatusBadRequest,
+			Message: fmt.Sprintf("at most %d metadata keys are allowed", maxJobMetadata)}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	return nil
+}
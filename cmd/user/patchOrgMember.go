@@ -0,0 +1,45 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"net/http"
+)
+
+// patchOrgMember changes a member's role. Only owners may grant or revoke ownership
+var patchOrgMember app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	oUUID, _, callerRole, appErr := orgAccess(r, db.OrgRoleAdmin)
+	if appErr != nil {
+		return appErr
+	}
+	aUUID, err := uuid.FromString(mux.Vars(r)["aID"])
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing member ID"}
+	}
+
+	role := db.OrgRole(r.URL.Query().Get("role"))
+	if !role.Valid() {
+		return &app.Error{Code: http.StatusBadRequest, Message: "role must be one of owner, admin, member or viewer"}
+	}
+	current, err := db.GetOrgRole(r, oUUID, aUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if current == "" {
+		return &app.Error{Code: http.StatusNotFound, Message: "member not found"}
+	} else if (role == db.OrgRoleOwner || current == db.OrgRoleOwner) && callerRole != db.OrgRoleOwner {
+		return &app.Error{Code: http.StatusForbidden, Message: "only owners may grant or revoke ownership"}
+	}
+
+	if err := db.SetOrgMemberRole(r, oUUID, aUUID, role); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "member not found"}
+	} else if err == db.ErrOrgLastOwner {
+		return &app.Error{Code: http.StatusConflict, Message: err.Error()}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	return nil
+}
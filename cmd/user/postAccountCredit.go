@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+)
+
+// postAccountCredit adds to (or, with a negative amount, deducts from) an account's credit,
+// optionally tied to a job
+var postAccountCredit app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	adminUUID, _, req, appErr := parseAdjustmentRequest(r)
+	if appErr != nil {
+		return appErr
+	}
+	aUUID, err := uuid.FromString(mux.Vars(r)["aID"])
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+	if req.Amount == 0 {
+		return &app.Error{Code: http.StatusBadRequest, Message: "amount can't be zero"}
+	}
+
+	adj, err := payments.AdjustCredit(r, aUUID, req.Job, req.Amount, req.Reason, adminUUID)
+	if err != nil {
+		return adjustmentError(err)
+	}
+
+	return writeAdjustment(w, r, adj)
+}
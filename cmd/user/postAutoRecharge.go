@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+)
+
+// postAutoRecharge sets the account's auto-recharge pack and threshold; an empty pack turns it off
+var postAutoRecharge app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	ar := autoRecharge{}
+	if err := json.NewDecoder(r.Body).Decode(&ar); err != nil {
+		log.Sugar.Infow("error decoding auto-recharge",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error decoding request body"}
+	}
+	if ar.Pack != "" {
+		if _, ok := payments.GetCreditPack(ar.Pack); !ok {
+			return &app.Error{Code: http.StatusBadRequest, Message: payments.ErrUnknownCreditPack.Error()}
+		}
+		if ar.Threshold <= 0 {
+			return &app.Error{Code: http.StatusBadRequest, Message: "threshold must be positive"}
+		}
+		stripeCustomerID, err := db.GetAccountStripeCustomerID(r, aUUID)
+		if err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+		} else if stripeCustomerID == "" {
+			return &app.Error{Code: http.StatusPaymentRequired, Message: "please add a payment method before turning on auto-recharge"}
+		}
+	}
+
+	if err := db.SetAutoRecharge(r, aUUID, ar.Pack, ar.Threshold); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	return nil
+}
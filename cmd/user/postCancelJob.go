@@ -11,7 +11,7 @@ import (
 	"github.com/wminshew/emrysserver/pkg/app"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
-	"github.com/wminshew/emrysserver/pkg/payments"
+	"github.com/wminshew/emrysserver/pkg/notify"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -52,6 +52,18 @@ var postCancelJob app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
 	}
 
+	if state, err := db.GetJobState(jUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if state.Terminal() {
+		log.Sugar.Infow("user tried to cancel a job that already ended",
+			"method", r.Method,
+			"url", r.URL,
+			"jID", jUUID,
+			"state", state,
+		)
+		return &app.Error{Code: http.StatusConflict, Message: fmt.Sprintf("job has already ended: %s", state)}
+	}
+
 	nbQuery := r.URL.Query().Get("notebook")
 	notebook := (nbQuery == "1")
 
@@ -250,22 +262,24 @@ var postCancelJob app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 				break
 			}
 
-			// check if job is still active [miner might fail here]
-			if active, err := db.GetJobActive(jUUID); err != nil {
-				log.Sugar.Errorw("error checking if job is active",
+			// check if job has ended [miner might fail or finish here]
+			if state, err := db.GetJobState(jUUID); err != nil {
+				return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+			} else if state == db.JobStateFailed {
+				log.Sugar.Errorw("miner failed to complete job during user cancellation",
 					"method", r.Method,
 					"url", r.URL,
-					"err", err.Error(),
 					"jID", jUUID,
 				)
-				return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
-			} else if !active {
-				log.Sugar.Errorw("miner failed to complete job during user cancellation",
+				return &app.Error{Code: http.StatusGone, Message: "the miner failed to upload your output. You will not be charged for this job accordingly"}
+			} else if state.Terminal() {
+				log.Sugar.Infow("job ended during user cancellation",
 					"method", r.Method,
 					"url", r.URL,
 					"jID", jUUID,
+					"state", state,
 				)
-				return &app.Error{Code: http.StatusGone, Message: "the miner failed to upload your output. You will not be charged for this job accordingly"}
+				return &app.Error{Code: http.StatusConflict, Message: fmt.Sprintf("job has already ended: %s", state)}
 			}
 
 			if pr.Timestamp > sinceTime {
@@ -278,15 +292,11 @@ var postCancelJob app.Handler = func(w http.ResponseWriter, r *http.Request) *ap
 		}
 	}
 
-	if err := db.SetJobCanceled(r, jUUID); err != nil {
+	if err := notify.CancelJob(r, jUUID, db.PaymentTaskChargeUser, db.PaymentTaskPayMiner); err == db.ErrInvalidJobStateTransition {
+		return &app.Error{Code: http.StatusConflict, Message: "job has already ended"} // already logged
+	} else if err != nil {
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
 	}
 
-	// no rate for user to pay if auction hasn't completed
-	if !auctionCompleted.IsZero() {
-		go payments.ChargeUser(r, stripeInvoiceItemC, jUUID)
-		go payments.PayMiner(r, stripeTransferC, jUUID)
-	}
-
 	return nil
 }
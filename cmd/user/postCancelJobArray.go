@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+)
+
+// postCancelJobArray cancels every job in one of the user's job arrays which hasn't ended,
+// and returns the array's status
+var postCancelJobArray app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	status, appErr := jobArrayStatusOf(r)
+	if appErr != nil {
+		return appErr
+	}
+
+	if err := db.SetJobArrayCanceled(r, status.ID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	project := mux.Vars(r)["project"]
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxJobArrayConcurrency)
+	for i := range status.Jobs {
+		member := &status.Jobs[i]
+		if member.State.Terminal() {
+			continue
+		}
+		u := url.URL{
+			Scheme: "http",
+			Host:   "user-svc:8080",
+			Path:   path.Join("user", "project", project, "job", member.ID.String(), "cancel"),
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := forwardRequest(r, http.MethodPost, u, nil); err != nil {
+				log.Sugar.Errorw("error canceling job array job",
+					"method", r.Method,
+					"url", r.URL,
+					"err", err.Error(),
+					"jID", member.ID,
+				)
+				member.Error = fmt.Sprintf("error canceling job: %v", err)
+				return
+			}
+			member.State = db.JobStateCanceled
+		}()
+	}
+	wg.Wait()
+
+	status.States = map[db.JobState]int{}
+	for _, member := range status.Jobs {
+		status.States[member.State]++
+	}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Sugar.Errorw("error encoding job array status",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	stripe "github.com/stripe/stripe-go"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+)
+
+// creditPurchase is a user's order for a credit pack. ID optionally names the purchase, so
+// retrying it after an error finishes it rather than charging again
+type creditPurchase struct {
+	ID   uuid.UUID `json:"id"`
+	Pack string    `json:"pack"`
+}
+
+type creditPurchaseReceipt struct {
+	ID     uuid.UUID `json:"id"`
+	Pack   string    `json:"pack"`
+	Price  int64     `json:"price"`
+	Credit int64     `json:"credit"`
+}
+
+// postCreditPurchase charges the account's card for a credit pack and adds its credit
+var postCreditPurchase app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	p := creditPurchase{}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		log.Sugar.Infow("error decoding credit purchase",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error decoding request body"}
+	}
+
+	if uuid.Equal(p.ID, uuid.Nil) {
+		p.ID = uuid.NewV4()
+	}
+	cp, err := payments.PurchaseCredit(r, stripeChargeC, p.ID, aUUID, p.Pack, uuid.Nil, nil)
+	if err == payments.ErrUnknownCreditPack {
+		return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+	} else if err == db.ErrCreditPurchaseExists {
+		return &app.Error{Code: http.StatusConflict, Message: "purchase id is already in use"}
+	} else if err == payments.ErrNoPaymentMethod {
+		return &app.Error{Code: http.StatusPaymentRequired, Message: "please add a payment method before buying credit"}
+	} else if stripeErr, ok := err.(*stripe.Error); ok && stripeErr.Type == stripe.ErrorTypeCard {
+		return &app.Error{Code: http.StatusPaymentRequired, Message: stripeErr.Msg}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	receipt := creditPurchaseReceipt{
+		ID:     cp.UUID,
+		Pack:   cp.Pack,
+		Price:  cp.Price,
+		Credit: cp.Credit,
+	}
+	if err := json.NewEncoder(w).Encode(&receipt); err != nil {
+		log.Sugar.Errorw("error encoding credit purchase receipt",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	return nil
+}
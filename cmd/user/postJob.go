@@ -9,10 +9,13 @@ import (
 	"github.com/wminshew/emrysserver/pkg/app"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/quota"
 	"github.com/wminshew/emrysserver/pkg/slack"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -20,7 +23,7 @@ const (
 	maxRetries = 10
 )
 
-// postJob handles new jobs posted by users
+// postJob handles new jobs posted by users. An org query creates the job in the organization's project
 var postJob app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
 	vars := mux.Vars(r)
 	project := vars["project"]
@@ -35,51 +38,38 @@ var postJob app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Erro
 		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
 	}
 
-	subID, err := db.GetAccountStripeSubscriptionID(r, uUUID)
-	if err != nil {
-		log.Sugar.Errorw("error getting stripe subscription ID",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-		)
-		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
-	} else if subID == "" {
-		log.Sugar.Errorw("user posted job with no stripe subscription",
-			"method", r.Method,
-			"url", r.URL,
-			"uID", uUUID,
-		)
-		return &app.Error{Code: http.StatusBadRequest, Message: "no payment information on file. " +
-			"Please verify your payment information on https://www.emrys.io/account and reach out to support if problems continue."}
+	oUUID, appErr := parseJobOrg(r, uUUID)
+	if appErr != nil {
+		return appErr
+	}
+	if appErr := checkSubscription(r, uUUID, oUUID); appErr != nil {
+		return appErr
+	}
+	if appErr := quota.Check(r, uUUID, oUUID); appErr != nil {
+		return appErr
 	}
 
-	sub, err := stripeSubC.Get(subID, nil)
-	if err != nil {
-		log.Sugar.Errorw("error getting stripe subscription",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-		)
-		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	q := r.URL.Query()
+	maxRuntime, maxSpend, appErr := parseJobLimits(r, uUUID)
+	if appErr != nil {
+		return appErr
 	}
 
-	if sub.Status != "active" {
-		log.Sugar.Errorw("user posted job with inactive stripe subscription",
-			"method", r.Method,
-			"url", r.URL,
-			"uID", uUUID,
-		)
-		return &app.Error{Code: http.StatusBadRequest, Message: "your stripe subscription is currently inactive. " +
-			"Please verify your payment information on https://www.emrys.io/account and reach out to support if problems continue."}
+	labels, err := parseJobLabels(q)
+	if err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
 	}
 
 	jobID := uuid.NewV4()
 	w.Header().Set("X-Job-ID", jobID.String())
 
-	nbQuery := r.URL.Query().Get("notebook")
+	nbQuery := q.Get("notebook")
 	notebook := (nbQuery == "1")
 
-	if err := db.InsertJob(r, uUUID, project, jobID, notebook); err != nil {
+	if err := db.InsertJob(r, uUUID, oUUID, project, jobID, notebook, maxRuntime, maxSpend, labels); err != nil {
+		if err == db.ErrProjectBusy {
+			return &app.Error{Code: http.StatusConflict, Message: "project is being deleted or renamed"}
+		}
 		log.Sugar.Errorw("error inserting job",
 			"method", r.Method,
 			"url", r.URL,
@@ -176,3 +166,88 @@ var postJob app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Erro
 
 	return nil
 }
+
+// checkSubscription returns an error unless user uUUID, or organization oUUID if it isn't
+// uuid.Nil, has an active stripe subscription
+func checkSubscription(r *http.Request, uUUID, oUUID uuid.UUID) *app.Error {
+	var subID string
+	var err error
+	if uuid.Equal(oUUID, uuid.Nil) {
+		subID, err = db.GetAccountStripeSubscriptionID(r, uUUID)
+	} else {
+		_, _, subID, err = db.GetOrgBilling(r, oUUID)
+	}
+	if err != nil {
+		log.Sugar.Errorw("error getting stripe subscription ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	} else if subID == "" {
+		log.Sugar.Errorw("user posted job with no stripe subscription",
+			"method", r.Method,
+			"url", r.URL,
+			"uID", uUUID,
+			"oID", oUUID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "no payment information on file. " +
+			"Please verify your payment information on https://www.emrys.io/account and reach out to support if problems continue."}
+	}
+
+	sub, err := stripeSubC.Get(subID, nil)
+	if err != nil {
+		log.Sugar.Errorw("error getting stripe subscription",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	if sub.Status != "active" {
+		log.Sugar.Errorw("user posted job with inactive stripe subscription",
+			"method", r.Method,
+			"url", r.URL,
+			"uID", uUUID,
+			"oID", oUUID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "your stripe subscription is currently inactive. " +
+			"Please verify your payment information on https://www.emrys.io/account and reach out to support if problems continue."}
+	}
+
+	return nil
+}
+
+// parseJobLimits parses the optional max-runtime (seconds) and max-spend (cents) job limits
+// from r's query
+func parseJobLimits(r *http.Request, uUUID uuid.UUID) (int64, int64, *app.Error) {
+	q := r.URL.Query()
+	var maxRuntime, maxSpend int64
+	if maxRuntimeStr := q.Get("max-runtime"); maxRuntimeStr != "" {
+		d, err := time.ParseDuration(maxRuntimeStr)
+		if err != nil || d < time.Second {
+			log.Sugar.Infow("invalid max runtime",
+				"method", r.Method,
+				"url", r.URL,
+				"uID", uUUID,
+			)
+			return 0, 0, &app.Error{Code: http.StatusBadRequest, Message: "max-runtime must be a duration of at least 1s (e.g. 90m, 12h)"}
+		}
+		maxRuntime = int64(d / time.Second)
+	}
+	if maxSpendStr := q.Get("max-spend"); maxSpendStr != "" {
+		dollars, err := strconv.ParseFloat(maxSpendStr, 64)
+		if err != nil || math.IsNaN(dollars) || dollars < 0.01 {
+			log.Sugar.Infow("invalid max spend",
+				"method", r.Method,
+				"url", r.URL,
+				"uID", uUUID,
+			)
+			return 0, 0, &app.Error{Code: http.StatusBadRequest, Message: "max-spend must be a dollar amount of at least 0.01"}
+		}
+		maxSpend = int64(math.Round(dollars * 100))
+	}
+
+	return maxRuntime, maxSpend, nil
+}
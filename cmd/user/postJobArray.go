@@ -0,0 +1,212 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrys/pkg/job"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/notify"
+	"github.com/wminshew/emrysserver/pkg/quota"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	maxJobArraySize        = 100
+	maxJobArrayArgs        = 64
+	maxJobArrayArgLen      = 1024
+	maxJobArrayConcurrency = 8
+)
+
+type jobArrayRequest struct {
+	Specs  *job.Specs `json:"specs"`
+	Params [][]string `json:"params"`
+}
+
+type jobArrayMember struct {
+	ID    uuid.UUID   `json:"id"`
+	Index int         `json:"index"`
+	Args  []string    `json:"args"`
+	State db.JobState `json:"state,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+type jobArrayResponse struct {
+	ID   uuid.UUID        `json:"id"`
+	Jobs []jobArrayMember `json:"jobs"`
+}
+
+// postJobArray fans job jID's built image and synced data set out into one auctioned job per
+// parameter set in the json body, each run with its args passed to main. specs default to
+// jID's if it was auctioned. A job built only to seed the array is canceled once it's submitted
+var postJobArray app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	project := vars["project"]
+	fromID := vars["jID"]
+	fromUUID, err := uuid.FromString(fromID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", fromID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	arrReq := jobArrayRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&arrReq); err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error decoding request body"}
+	}
+	if len(arrReq.Params) == 0 || len(arrReq.Params) > maxJobArraySize {
+		return &app.Error{Code: http.StatusBadRequest,
+			Message: fmt.Sprintf("params must have between 1 and %d parameter sets", maxJobArraySize)}
+	}
+	for i, args := range arrReq.Params {
+		if len(args) > maxJobArrayArgs {
+			return &app.Error{Code: http.StatusBadRequest,
+				Message: fmt.Sprintf("parameter set %d has more than %d args", i, maxJobArrayArgs)}
+		}
+		for _, arg := range args {
+			if utf8.RuneCountInString(arg) > maxJobArrayArgLen {
+				return &app.Error{Code: http.StatusBadRequest,
+					Message: fmt.Sprintf("parameter set %d has an arg longer than %d characters", i, maxJobArrayArgLen)}
+			}
+		}
+		if args == nil {
+			arrReq.Params[i] = []string{}
+		}
+	}
+
+	if notebook, err := db.GetJobNotebook(fromUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if notebook {
+		return &app.Error{Code: http.StatusBadRequest, Message: "notebook jobs can't be run as arrays"}
+	}
+
+	fromSpecs, err := db.GetJobSpecs(r, fromUUID)
+	fromAuctioned := (err == nil)
+	if err != nil && err != sql.ErrNoRows {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	specs := arrReq.Specs
+	if specs == nil {
+		if !fromAuctioned {
+			return &app.Error{Code: http.StatusBadRequest, Message: "specs are required"}
+		}
+		specs = fromSpecs
+	}
+
+	oUUID, err := db.GetJobOrg(r, fromUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	if appErr := checkOrgJobRole(r, oUUID, uUUID); appErr != nil {
+		return appErr
+	}
+	if appErr := checkSubscription(r, uUUID, oUUID); appErr != nil {
+		return appErr
+	}
+	if appErr := quota.Check(r, uUUID, oUUID); appErr != nil {
+		return appErr
+	}
+
+	q := r.URL.Query()
+	maxRuntime, maxSpend, appErr := parseJobLimits(r, uUUID)
+	if appErr != nil {
+		return appErr
+	}
+
+	var labels *db.JobLabels
+	if _, ok := q["name"]; ok || len(q["tag"]) > 0 || len(q["meta"]) > 0 {
+		if labels, err = parseJobLabels(q); err != nil {
+			return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+	} else if labels, err = db.GetJobLabels(r, fromUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	arrUUID := uuid.NewV4()
+	w.Header().Set("X-Job-Array-ID", arrUUID.String())
+	if err := db.InsertJobArray(r, arrUUID, uUUID, fromUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	resp := jobArrayResponse{
+		ID:   arrUUID,
+		Jobs: make([]jobArrayMember, len(arrReq.Params)),
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxJobArrayConcurrency)
+	for i, args := range arrReq.Params {
+		memberLabels := *labels
+		if memberLabels.Name != "" {
+			memberLabels.Name = fmt.Sprintf("%s[%d]", labels.Name, i)
+		}
+		memberSpecs := *specs
+		rr := &rerun{
+			project:    project,
+			org:        oUUID,
+			from:       fromUUID,
+			specs:      &memberSpecs,
+			labels:     &memberLabels,
+			maxRuntime: maxRuntime,
+			maxSpend:   maxSpend,
+			args:       args,
+			array:      arrUUID,
+			arrayIndex: i,
+		}
+		member := &resp.Jobs[i]
+		member.ID, member.Index, member.Args = uuid.NewV4(), i, args
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if appErr := rr.launch(r, uUUID, member.ID); appErr != nil {
+				member.Error = appErr.Message
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !fromAuctioned {
+		if err := notify.CancelJob(r, fromUUID); err != nil && err != db.ErrInvalidJobStateTransition {
+			log.Sugar.Errorw("error canceling job array source job",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", fromID,
+			)
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+		log.Sugar.Errorw("error encoding job array",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+)
+
+// postJobRefund refunds some or all of a job's charge to its payer, either against their next
+// invoice (method=invoice) or as account credit (method=credit)
+var postJobRefund app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	adminUUID, jUUID, req, appErr := parseAdjustmentRequest(r)
+	if appErr != nil {
+		return appErr
+	}
+	if req.Method != payments.RefundInvoice && req.Method != payments.RefundCredit {
+		return &app.Error{Code: http.StatusBadRequest, Message: "method must be invoice or credit"}
+	}
+
+	adj, err := payments.RefundUser(r, stripeInvoiceItemC, req.ID, jUUID, req.Amount, req.Method, req.Reason, adminUUID)
+	if err != nil {
+		return adjustmentError(err)
+	}
+
+	return writeAdjustment(w, r, adj)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/payments"
+	"net/http"
+)
+
+// postJobReverseTransfer claws some or all of a job's payout back from its miner
+var postJobReverseTransfer app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	adminUUID, jUUID, req, appErr := parseAdjustmentRequest(r)
+	if appErr != nil {
+		return appErr
+	}
+
+	adj, err := payments.ReverseMinerTransfer(r, stripeReversalC, req.ID, jUUID, req.Amount, req.Reason, adminUUID)
+	if err != nil {
+		return adjustmentError(err)
+	}
+
+	return writeAdjustment(w, r, adj)
+}
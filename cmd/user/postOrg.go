@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"net/mail"
+)
+
+// postOrg creates an organization owned by the user. Its stripe customer is billed at
+// billing-email, which defaults to the user's email
+var postOrg app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	q := r.URL.Query()
+	name := q.Get("name")
+	if err := validateOrgName(name); err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	billingEmail := q.Get("billing-email")
+	if billingEmail == "" {
+		if billingEmail, err = db.GetAccountEmail(r, uUUID); err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+		}
+	} else if _, err := mail.ParseAddress(billingEmail); err != nil {
+		return &app.Error{Code: http.StatusBadRequest, Message: "invalid billing-email"}
+	}
+
+	o := org{
+		ID:   uuid.NewV4(),
+		Name: name,
+		Role: db.OrgRoleOwner,
+	}
+	if err := db.InsertOrg(r, o.ID, o.Name, billingEmail, uUUID); err == db.ErrOrgExists {
+		return &app.Error{Code: http.StatusConflict, Message: err.Error()}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	if err := json.NewEncoder(w).Encode(&o); err != nil {
+		log.Sugar.Errorw("error encoding org",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// postOrgMember adds the user with email to the organization as role (default member).
+// Only owners may add owners
+var postOrgMember app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	oUUID, _, callerRole, appErr := orgAccess(r, db.OrgRoleAdmin)
+	if appErr != nil {
+		return appErr
+	}
+
+	q := r.URL.Query()
+	email := q.Get("email")
+	if email == "" {
+		return &app.Error{Code: http.StatusBadRequest, Message: "email is required"}
+	}
+	role := db.OrgRoleMember
+	if roleStr := q.Get("role"); roleStr != "" {
+		role = db.OrgRole(roleStr)
+	}
+	if !role.Valid() {
+		return &app.Error{Code: http.StatusBadRequest, Message: "role must be one of owner, admin, member or viewer"}
+	} else if role == db.OrgRoleOwner && callerRole != db.OrgRoleOwner {
+		return &app.Error{Code: http.StatusForbidden, Message: "only owners may add owners"}
+	}
+
+	m := orgMember{
+		Email:    email,
+		Role:     role,
+		JoinedAt: time.Now(),
+	}
+	var err error
+	if m.ID, err = db.InsertOrgMember(r, oUUID, email, role); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "no user with that email"}
+	} else if err == db.ErrOrgMemberExists {
+		return &app.Error{Code: http.StatusConflict, Message: err.Error()}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	if err := json.NewEncoder(w).Encode(&m); err != nil {
+		log.Sugar.Errorw("error encoding org member",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
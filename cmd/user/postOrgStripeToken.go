@@ -0,0 +1,99 @@
+package main
+
+import (
+	stripe "github.com/stripe/stripe-go"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// postOrgStripeToken creates or updates the organization's stripe customer with payment info,
+// and if appropriate subscribes it to emrys-user-access. Jobs in org projects are billed here
+var postOrgStripeToken app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	oUUID, _, _, appErr := orgAccess(r, db.OrgRoleAdmin)
+	if appErr != nil {
+		return appErr
+	}
+
+	stripeToken := r.URL.Query().Get("stripeToken")
+	if stripeToken == "" {
+		log.Sugar.Errorw("error retrieving stripe token from form",
+			"method", r.Method,
+			"url", r.URL,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "no stripe token included in request"}
+	}
+
+	billingEmail, stripeCustomerID, stripeSubID, err := db.GetOrgBilling(r, oUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	customerParams := &stripe.CustomerParams{}
+	if err := customerParams.SetSource(stripeToken); err != nil {
+		log.Sugar.Errorw("error setting customer source with stripe token",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	if stripeCustomerID != "" {
+		if _, err := stripeCustomerC.Update(stripeCustomerID, customerParams); err != nil {
+			log.Sugar.Errorw("error updating customer source with stripe token",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+	} else {
+		customerParams.Email = stripe.String(billingEmail)
+		cus, err := stripeCustomerC.New(customerParams)
+		if err != nil {
+			log.Sugar.Errorw("error creating new stripe customer",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+		stripeCustomerID = cus.ID
+
+		if err := db.SetOrgStripeCustomerID(r, oUUID, stripeCustomerID); err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+		}
+	}
+
+	if stripeSubID == "" {
+		t := time.Now()
+		begOfNextMonth := time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		subItems := []*stripe.SubscriptionItemsParams{
+			{
+				Plan: stripe.String(stripePlanID),
+			},
+		}
+		subParams := &stripe.SubscriptionParams{
+			BillingCycleAnchor: stripe.Int64(begOfNextMonth.Unix()),
+			Customer:           stripe.String(stripeCustomerID),
+			Items:              subItems,
+		}
+		subscription, err := stripeSubC.New(subParams)
+		if err != nil {
+			log.Sugar.Errorw("error creating new stripe subscription",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+
+		if err := db.SetOrgStripeSubscriptionID(r, oUUID, subscription.ID); err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+		}
+	}
+
+	return nil
+}
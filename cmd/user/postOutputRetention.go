@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"strconv"
+)
+
+// postOutputRetention sets how long (days) and how much (bytes) job output the account keeps in
+// storage. Only the limits given are changed
+var postOutputRetention app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	q := r.URL.Query()
+	var days, maxBytes *int64
+	if daysStr := q.Get("days"); daysStr != "" {
+		d, err := strconv.ParseInt(daysStr, 10, 64)
+		if err != nil || d < 0 {
+			log.Sugar.Infow("invalid output retention days",
+				"method", r.Method,
+				"url", r.URL,
+				"aID", aUUID,
+			)
+			return &app.Error{Code: http.StatusBadRequest, Message: "days must be a non-negative integer"}
+		}
+		days = &d
+	}
+	if maxBytesStr := q.Get("max-bytes"); maxBytesStr != "" {
+		b, err := strconv.ParseInt(maxBytesStr, 10, 64)
+		if err != nil || b < 0 {
+			log.Sugar.Infow("invalid output retention max bytes",
+				"method", r.Method,
+				"url", r.URL,
+				"aID", aUUID,
+			)
+			return &app.Error{Code: http.StatusBadRequest, Message: "max-bytes must be a non-negative integer"}
+		}
+		maxBytes = &b
+	}
+	if days == nil && maxBytes == nil {
+		log.Sugar.Infow("no output retention limits given",
+			"method", r.Method,
+			"url", r.URL,
+			"aID", aUUID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "days or max-bytes is required"}
+	}
+
+	if err := db.SetAccountOutputRetention(r, aUUID, days, maxBytes); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	return nil
+}
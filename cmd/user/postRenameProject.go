@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// postRenameProject renames the user's project to the name in the query, moving its cached
+// data set. The old project's images are removed and rebuilt under the new name by its next job
+var postRenameProject app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	vars := mux.Vars(r)
+	project := vars["project"]
+	name := r.URL.Query().Get("name")
+	if !projectRegexp.MatchString(name) {
+		return &app.Error{Code: http.StatusBadRequest, Message: "invalid project name"}
+	} else if name == project {
+		return nil
+	}
+
+	if _, err := db.GetProjectActiveJobs(r, uUUID, name); err == nil {
+		return &app.Error{Code: http.StatusConflict, Message: "project already exists"}
+	} else if err != sql.ErrNoRows {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	// no new jobs can be posted to the project while its data is moved and images deleted
+	if err := db.SetProjectBusy(r, uUUID, project, db.ProjectRenaming); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "project not found"}
+	} else if err == db.ErrProjectActive {
+		return &app.Error{Code: http.StatusConflict, Message: "project has active jobs"}
+	} else if err == db.ErrProjectBusy {
+		return &app.Error{Code: http.StatusConflict, Message: "project is being deleted"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	u := url.URL{
+		Scheme: "http",
+		Host:   "data-svc:8080",
+		Path:   path.Join("project", project, "rename"),
+	}
+	q := u.Query()
+	q.Set("name", name)
+	u.RawQuery = q.Encode()
+	if err := forwardRequest(r, http.MethodPost, u, nil); err != nil {
+		log.Sugar.Errorw("error moving project data--aborting",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"project", project,
+		)
+		_ = db.ClearProjectBusy(r, uUUID, project) // already logged
+		return &app.Error{Code: http.StatusInternalServerError, Message: "error moving project data"}
+	}
+
+	u = url.URL{
+		Scheme: "http",
+		Host:   "image-svc:8080",
+		Path:   path.Join("image", project),
+	}
+	if err := forwardRequest(r, http.MethodDelete, u, nil); err != nil {
+		log.Sugar.Errorw("error deleting project images--aborting",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"project", project,
+		)
+		_ = db.ClearProjectBusy(r, uUUID, project) // already logged
+		return &app.Error{Code: http.StatusInternalServerError, Message: "error deleting project images"}
+	}
+
+	if err := db.RenameProject(r, uUUID, project, name); err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusNotFound, Message: "project not found"}
+	} else if err == db.ErrProjectActive {
+		return &app.Error{Code: http.StatusConflict, Message: "project has active jobs"}
+	} else if err == db.ErrProjectExists {
+		return &app.Error{Code: http.StatusConflict, Message: "project already exists"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	return nil
+}
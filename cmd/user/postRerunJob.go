@@ -0,0 +1,224 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrys/pkg/job"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/notify"
+	"github.com/wminshew/emrysserver/pkg/quota"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// postRerunJob creates and auctions a new job from job jID's built image, data set and specs.
+// Fields in an optional json.Specs body override the previous specs, and name, tag and meta
+// queries replace its labels. The new job stays in the source job's organization, if any
+var postRerunJob app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	vars := mux.Vars(r)
+	project := vars["project"]
+	fromID := vars["jID"]
+	fromUUID, err := uuid.FromString(fromID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing job ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+	}
+	uID := r.Header.Get("X-Jwt-Claims-Subject")
+	uUUID, err := uuid.FromString(uID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing user ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", fromID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing user ID"}
+	}
+
+	if notebook, err := db.GetJobNotebook(fromUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if notebook {
+		return &app.Error{Code: http.StatusBadRequest, Message: "notebook jobs can't be re-run"}
+	}
+
+	specs, err := db.GetJobSpecs(r, fromUUID)
+	if err == sql.ErrNoRows {
+		return &app.Error{Code: http.StatusConflict, Message: "job was never auctioned"}
+	} else if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	if err := json.NewDecoder(r.Body).Decode(specs); err != nil && err != io.EOF {
+		return &app.Error{Code: http.StatusBadRequest, Message: "error decoding request body"}
+	}
+
+	oUUID, err := db.GetJobOrg(r, fromUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	if appErr := checkOrgJobRole(r, oUUID, uUUID); appErr != nil {
+		return appErr
+	}
+	if appErr := checkSubscription(r, uUUID, oUUID); appErr != nil {
+		return appErr
+	}
+	if appErr := quota.Check(r, uUUID, oUUID); appErr != nil {
+		return appErr
+	}
+
+	q := r.URL.Query()
+	maxRuntime, maxSpend, appErr := parseJobLimits(r, uUUID)
+	if appErr != nil {
+		return appErr
+	}
+
+	var labels *db.JobLabels
+	if _, ok := q["name"]; ok || len(q["tag"]) > 0 || len(q["meta"]) > 0 {
+		if labels, err = parseJobLabels(q); err != nil {
+			return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+	} else if labels, err = db.GetJobLabels(r, fromUUID); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	jUUID := uuid.NewV4()
+	w.Header().Set("X-Job-ID", jUUID.String())
+
+	rr := &rerun{
+		project:    project,
+		org:        oUUID,
+		from:       fromUUID,
+		specs:      specs,
+		labels:     labels,
+		maxRuntime: maxRuntime,
+		maxSpend:   maxSpend,
+	}
+	return rr.launch(r, uUUID, jUUID)
+}
+
+// rerun describes a job created from an earlier job's image and data set
+type rerun struct {
+	project    string
+	org        uuid.UUID // organization owning project, if not uuid.Nil
+	from       uuid.UUID
+	specs      *job.Specs
+	labels     *db.JobLabels
+	maxRuntime int64
+	maxSpend   int64
+	args       []string  // replaces the image's command unless nil
+	array      uuid.UUID // job array the job belongs to, if not uuid.Nil
+	arrayIndex int
+}
+
+// launch inserts job jUUID for user uUUID, re-uses rr.from's image and data set and auctions it
+func (rr *rerun) launch(r *http.Request, uUUID, jUUID uuid.UUID) *app.Error {
+	jID := jUUID.String()
+	if err := db.InsertJob(r, uUUID, rr.org, rr.project, jUUID, false, rr.maxRuntime, rr.maxSpend, rr.labels); err != nil {
+		if err == db.ErrProjectBusy {
+			return &app.Error{Code: http.StatusConflict, Message: "project is being deleted or renamed"}
+		}
+		log.Sugar.Errorw("error inserting job",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "error inserting job"}
+	}
+	if !uuid.Equal(rr.array, uuid.Nil) {
+		if err := db.SetJobArrayMember(r, jUUID, rr.array, rr.arrayIndex, rr.args); err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+		}
+	}
+
+	var imageBody []byte
+	if rr.args != nil {
+		var err error
+		if imageBody, err = json.Marshal(struct {
+			Args []string `json:"args"`
+		}{rr.args}); err != nil {
+			log.Sugar.Errorw("error encoding job args",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jID,
+			)
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+		}
+	}
+	fromQ := url.Values{}
+	fromQ.Set("from", rr.from.String())
+	for _, step := range []struct {
+		u       url.URL
+		body    []byte
+		message string
+	}{
+		{
+			u: url.URL{
+				Scheme:   "http",
+				Host:     "image-svc:8080",
+				Path:     path.Join("image", rr.project, jID, "rerun"),
+				RawQuery: fromQ.Encode(),
+			},
+			body:    imageBody,
+			message: "error re-using job image",
+		},
+		{
+			u: url.URL{
+				Scheme:   "http",
+				Host:     "data-svc:8080",
+				Path:     path.Join("user", "project", rr.project, "job", jID, "rerun"),
+				RawQuery: fromQ.Encode(),
+			},
+			message: "error re-using job data set",
+		},
+	} {
+		if err := forwardRequest(r, http.MethodPost, step.u, step.body); err != nil {
+			log.Sugar.Errorw(fmt.Sprintf("%s--aborting", step.message),
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jID,
+			)
+			// jobs can only fail once auctioned, so cancel the re-run rather than leave it pending
+			_ = notify.CancelJob(r, jUUID) // already logged
+			return forwardedAppError(err, step.message)
+		}
+	}
+
+	body, err := json.Marshal(rr.specs)
+	if err != nil {
+		log.Sugar.Errorw("error encoding job specs",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	u := url.URL{
+		Scheme: "http",
+		Host:   "miner-svc:8080",
+		Path:   path.Join("auction", jID),
+	}
+	if err := forwardRequest(r, http.MethodPost, u, body); err != nil {
+		log.Sugar.Errorw("error auctioning re-run job",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jID,
+		)
+		return forwardedAppError(err, "error auctioning job")
+	}
+
+	return nil
+}
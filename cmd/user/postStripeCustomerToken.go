@@ -127,6 +127,11 @@ var postStripeCustomerToken app.Handler = func(w http.ResponseWriter, r *http.Re
 		if err := db.SetAccountStripeSubscriptionID(r, aUUID, subscription.ID); err != nil {
 			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
 		}
+
+		// accounts are suspended when their subscription is deleted; they can be billed again
+		if _, err := db.SetCustomerPaymentSuspended(r, stripeCustomerID, false); err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+		}
 	}
 
 	return nil
@@ -1,9 +1,6 @@
 package main
 
 import (
-	// "fmt"
-	// "github.com/satori/go.uuid"
-	// stripe "github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/webhook"
 	"github.com/wminshew/emrysserver/pkg/app"
 	"github.com/wminshew/emrysserver/pkg/log"
@@ -25,17 +22,13 @@ var postStripeWebhookAccount app.Handler = func(w http.ResponseWriter, r *http.R
 
 	event, err := webhook.ConstructEvent(body, r.Header.Get("Stripe-Signature"), stripeWebhookSecretAccount)
 	if err != nil {
-		log.Sugar.Errorw("error verrifying stripe webhook signature",
+		log.Sugar.Errorw("error verifying stripe webhook signature",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
 		)
-		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+		return &app.Error{Code: http.StatusBadRequest, Message: "error verifying stripe webhook signature"}
 	}
 
-	go func() {
-		log.Sugar.Infof("%+v", event)
-	}()
-
-	return nil
+	return processStripeEvent(r, &event)
 }
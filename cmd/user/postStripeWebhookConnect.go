@@ -3,7 +3,6 @@ package main
 import (
 	"github.com/stripe/stripe-go/webhook"
 	"github.com/wminshew/emrysserver/pkg/app"
-	"github.com/wminshew/emrysserver/pkg/email"
 	"github.com/wminshew/emrysserver/pkg/log"
 	"io/ioutil"
 	"net/http"
@@ -28,40 +27,8 @@ var postStripeWebhookConnect app.Handler = func(w http.ResponseWriter, r *http.R
 			"url", r.URL,
 			"err", err.Error(),
 		)
-		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing job ID"}
+		return &app.Error{Code: http.StatusBadRequest, Message: "error verifying stripe webhook signature"}
 	}
 
-	go func() {
-		log.Sugar.Infof("%+v", event)
-
-		switch event.Type {
-		case "payout.paid":
-			log.Sugar.Infow("payout.paid",
-				"amt", event.GetObjectValue("amount"),
-				"tx", event.GetObjectValue("balance_transaction"),
-				"dest", event.GetObjectValue("destination"),
-				"status", event.GetObjectValue("status"),
-			)
-		case "payout.failed":
-			amt := event.GetObjectValue("amount")
-			dest := event.GetObjectValue("destination")
-			log.Sugar.Errorw("payout.failed",
-				"amt", amt,
-				"tx", event.GetObjectValue("balance_transaction"),
-				"dest", dest,
-				"status", event.GetObjectValue("status"),
-			)
-			if err := email.SendPayoutFailed(dest, amt); err != nil {
-				log.Sugar.Errorw("error sending payout-failed email to support",
-					"method", r.Method,
-					"url", r.URL,
-					"err", err.Error(),
-				)
-				return
-			}
-		default:
-		}
-	}()
-
-	return nil
+	return processStripeEvent(r, &event)
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/notify"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookSecretBytes = 32
+)
+
+// postWebhook registers a webhook url for the account's job notifications. The
+// signing secret is only returned here
+var postWebhook app.Handler = func(w http.ResponseWriter, r *http.Request) *app.Error {
+	aID := r.Header.Get("X-Jwt-Claims-Subject")
+	aUUID, err := uuid.FromString(aID)
+	if err != nil {
+		log.Sugar.Errorw("error parsing account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: "error parsing account ID"}
+	}
+
+	whURL := r.URL.Query().Get("url")
+	if err := notify.ValidateWebhookURL(r.Context(), whURL); err != nil {
+		log.Sugar.Infow("invalid webhook url",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"aID", aUUID,
+		)
+		return &app.Error{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	b := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		log.Sugar.Errorw("error generating webhook secret",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+	wh := userWebhook{
+		ID:        uuid.NewV4(),
+		URL:       whURL,
+		Secret:    hex.EncodeToString(b),
+		CreatedAt: time.Now(),
+	}
+
+	if err := db.InsertWebhook(r, aUUID, wh.ID, wh.URL, wh.Secret); err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	if err := json.NewEncoder(w).Encode(&wh); err != nil {
+		log.Sugar.Errorw("error encoding webhook",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+		)
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+
+	return nil
+}
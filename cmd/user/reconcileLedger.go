@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"github.com/satori/go.uuid"
+	stripe "github.com/stripe/stripe-go"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+const (
+	ledgerReconcilePeriod = 24 * time.Hour
+	ledgerReconcileWindow = 7 * 24 * time.Hour
+)
+
+type ledgerStripeTxn struct {
+	txnUUID  uuid.UUID
+	kind     db.LedgerKind
+	job      uuid.NullUUID
+	stripeID string
+	amount   int64
+}
+
+// reconcileLedger compares recent ledger transactions made through stripe against the stripe
+// objects they reference, logging any which are missing or disagree on amount
+func reconcileLedger() error {
+	txns := []ledgerStripeTxn{}
+	if err := func() error {
+		rows, err := db.GetLedgerStripeTxns(time.Now().Add(-ledgerReconcileWindow))
+		if err != nil {
+			return err // already logged
+		}
+		defer func() {
+			if err := rows.Close(); err != nil {
+				log.Sugar.Errorf("Error closing rows")
+			}
+		}()
+
+		for rows.Next() {
+			lt := ledgerStripeTxn{}
+			if err := rows.Scan(&lt.txnUUID, &lt.kind, &lt.job, &lt.stripeID, &lt.amount); err != nil {
+				return err
+			}
+			txns = append(txns, lt)
+		}
+		return rows.Err()
+	}(); err != nil {
+		return err
+	}
+
+	mismatches := 0
+	for _, lt := range txns {
+		amount, err := stripeAmount(lt)
+		if err != nil {
+			log.Sugar.Errorw("error retrieving stripe object for ledger txn",
+				"err", err.Error(),
+				"txn", lt.txnUUID,
+				"kind", lt.kind,
+				"stripeID", lt.stripeID,
+			)
+			mismatches++
+			continue
+		}
+		if amount != lt.amount {
+			log.Sugar.Errorw("ledger txn doesn't match stripe",
+				"txn", lt.txnUUID,
+				"kind", lt.kind,
+				"stripeID", lt.stripeID,
+				"ledgerAmount", lt.amount,
+				"stripeAmount", amount,
+			)
+			mismatches++
+		}
+	}
+
+	log.Sugar.Infow("reconciled ledger against stripe",
+		"txns", len(txns),
+		"mismatches", mismatches,
+	)
+	return nil
+}
+
+// stripeAmount returns the amount (cents) stripe holds for the object behind ledger transaction
+// lt. Deleted invoice items are worth nothing
+func stripeAmount(lt ledgerStripeTxn) (int64, error) {
+	id := lt.stripeID
+	switch lt.kind {
+	case db.LedgerKindJobCharge:
+		ii, err := stripeInvoiceItemC.Get(id, nil)
+		if err != nil {
+			return 0, err
+		} else if ii.Deleted {
+			return 0, nil
+		}
+		return ii.Amount, nil
+	case db.LedgerKindMinerPayout, db.LedgerKindCollateralRelease:
+		t, err := stripeTransferC.Get(id, nil)
+		if err != nil {
+			return 0, err
+		}
+		return t.Amount, nil
+	case db.LedgerKindPenalty, db.LedgerKindCreditPurchase:
+		ch, err := stripeChargeC.Get(id, nil)
+		if err != nil {
+			return 0, err
+		}
+		return ch.Amount, nil
+	case db.LedgerKindRefund:
+		ii, err := stripeInvoiceItemC.Get(id, nil)
+		if err != nil {
+			return 0, err
+		} else if ii.Deleted {
+			return 0, nil
+		}
+		return -ii.Amount, nil
+	case db.LedgerKindTransferReversal:
+		if !lt.job.Valid {
+			return 0, fmt.Errorf("transfer reversal without a job")
+		}
+		ri, err := db.GetJobRefundInfo(lt.job.UUID)
+		if err != nil {
+			return 0, err
+		}
+		rev, err := stripeReversalC.Get(id, &stripe.ReversalParams{Transfer: stripe.String(ri.MinerTransferID)})
+		if err != nil {
+			return 0, err
+		}
+		return rev.Amount, nil
+	}
+	return 0, fmt.Errorf("can't reconcile ledger kind %s", lt.kind)
+}
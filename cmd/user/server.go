@@ -8,9 +8,11 @@ import (
 	"github.com/rs/cors"
 	stripe "github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/account"
+	"github.com/stripe/stripe-go/charge"
 	"github.com/stripe/stripe-go/customer"
 	"github.com/stripe/stripe-go/invoiceitem"
 	"github.com/stripe/stripe-go/loginlink"
+	"github.com/stripe/stripe-go/reversal"
 	"github.com/stripe/stripe-go/sub"
 	"github.com/stripe/stripe-go/transfer"
 	"github.com/wminshew/emrys/pkg/validate"
@@ -18,10 +20,12 @@ import (
 	"github.com/wminshew/emrysserver/pkg/auth"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
+	"github.com/wminshew/emrysserver/pkg/payments"
 	sheets "google.golang.org/api/sheets/v4"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"syscall"
 	"time"
 )
@@ -35,6 +39,8 @@ var (
 	stripePlanID               = os.Getenv("STRIPE_USER_PLAN_ID")
 	stripeInvoiceItemC         *invoiceitem.Client
 	stripeTransferC            *transfer.Client
+	stripeChargeC              *charge.Client
+	stripeReversalC            *reversal.Client
 	stripeLoginLinkC           *loginlink.Client
 	stripeAccountC             *account.Client
 	stripeSubC                 *sub.Client
@@ -42,6 +48,7 @@ var (
 	debugCors                  = (os.Getenv("DEBUG_CORS") == "true")
 	debugLog                   = (os.Getenv("DEBUG_LOG") == "true")
 	sheetsService              *sheets.Service
+	projectRegexp              *regexp.Regexp
 )
 
 func main() {
@@ -89,9 +96,50 @@ func main() {
 		B:   stripe.GetBackendWithConfig(stripe.APIBackend, stripeConfig),
 		Key: stripeSecretKey,
 	}
+	stripeChargeC = &charge.Client{
+		B:   stripe.GetBackendWithConfig(stripe.APIBackend, stripeConfig),
+		Key: stripeSecretKey,
+	}
+	stripeReversalC = &reversal.Client{
+		B:   stripe.GetBackendWithConfig(stripe.APIBackend, stripeConfig),
+		Key: stripeSecretKey,
+	}
+
+	done := make(chan struct{}, 1)
+	go func() {
+		for {
+			if err := reconcileLedger(); err != nil {
+				log.Sugar.Errorf("Error reconciling ledger: %v\n", err)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(ledgerReconcilePeriod):
+			}
+		}
+	}()
+
+	paymentsWorker := &payments.Worker{
+		InvoiceItemC: stripeInvoiceItemC,
+		TransferC:    stripeTransferC,
+		ChargeC:      stripeChargeC,
+	}
+	go func() {
+		for {
+			if err := paymentsWorker.Run(); err != nil {
+				log.Sugar.Errorf("Error running payment tasks: %v\n", err)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(payments.WorkerPeriod):
+			}
+		}
+	}()
 
 	uuidRegexpMux := validate.UUIDRegexpMux()
 	projectRegexpMux := validate.ProjectRegexpMux()
+	projectRegexp = regexp.MustCompile(fmt.Sprintf("^%s$", projectRegexpMux))
 
 	r := mux.NewRouter()
 	r.NotFoundHandler = http.HandlerFunc(app.APINotFound)
@@ -104,9 +152,49 @@ func main() {
 	rUser := r.PathPrefix("/user").Subrouter()
 	rUser.Handle("/version", getVersion).Methods(http.MethodGet)
 	rUser.Handle("/job-history", auth.Jwt(authSecret, []string{})(getJobHistory)).Methods(http.MethodGet)
+	rUser.Handle("/job-search", auth.Jwt(authSecret, []string{})(getJobSearch)).Methods(http.MethodGet)
 	rUser.Handle("/credit", auth.Jwt(authSecret, []string{})(getAccountCredit)).Methods(http.MethodGet)
+	rUser.Handle("/credit/history", auth.Jwt(authSecret, []string{})(getCreditHistory)).Methods(http.MethodGet)
+	rUser.Handle("/credit/packs", auth.Jwt(authSecret, []string{"user"})(getCreditPacks)).Methods(http.MethodGet)
+	rUser.Handle("/credit/purchase", auth.Jwt(authSecret, []string{"user"})(postCreditPurchase)).Methods(http.MethodPost)
+	rUser.Handle("/credit/auto-recharge", auth.Jwt(authSecret, []string{"user"})(getAutoRecharge)).Methods(http.MethodGet)
+	rUser.Handle("/credit/auto-recharge", auth.Jwt(authSecret, []string{"user"})(postAutoRecharge)).Methods(http.MethodPost)
+	rUser.Handle("/usage", auth.Jwt(authSecret, []string{"user"})(getUsage)).Methods(http.MethodGet)
+	rUser.Handle("/usage/report", auth.Jwt(authSecret, []string{"user"})(getUsageReport)).Methods(http.MethodGet)
 	rUser.Handle("/email", auth.Jwt(authSecret, []string{})(getAccountEmail)).Methods(http.MethodGet)
 	rUser.Handle("/feedback", auth.Jwt(authSecret, []string{})(postFeedback)).Methods(http.MethodPost)
+	rUser.Handle("/output-retention", auth.Jwt(authSecret, []string{"user"})(getOutputRetention)).Methods(http.MethodGet)
+	rUser.Handle("/output-retention", auth.Jwt(authSecret, []string{"user"})(postOutputRetention)).Methods(http.MethodPost)
+	rUser.Handle("/webhook", auth.Jwt(authSecret, []string{"user"})(getWebhooks)).Methods(http.MethodGet)
+	rUser.Handle("/webhook", auth.Jwt(authSecret, []string{"user"})(postWebhook)).Methods(http.MethodPost)
+	rUser.Handle(fmt.Sprintf("/webhook/{whID:%s}", uuidRegexpMux),
+		auth.Jwt(authSecret, []string{"user"})(deleteWebhook)).Methods(http.MethodDelete)
+	rUser.Handle("/notify-email", auth.Jwt(authSecret, []string{"user"})(getNotifyEmail)).Methods(http.MethodGet)
+	rUser.Handle("/notify-email", auth.Jwt(authSecret, []string{"user"})(postNotifyEmail)).Methods(http.MethodPost)
+
+	rUser.Handle("/project", auth.Jwt(authSecret, []string{"user"})(getProjects)).Methods(http.MethodGet)
+	projectPath := fmt.Sprintf("/project/{project:%s}", projectRegexpMux)
+	rUser.Handle(projectPath, auth.Jwt(authSecret, []string{"user"})(deleteProject)).Methods(http.MethodDelete)
+	rUser.Handle(projectPath+"/rename",
+		auth.Jwt(authSecret, []string{"user"})(postRenameProject)).Methods(http.MethodPost)
+
+	jobArrayPath := fmt.Sprintf("/project/{project:%s}/array/{arrID:%s}", projectRegexpMux, uuidRegexpMux)
+	rUser.Handle(jobArrayPath, auth.Jwt(authSecret, []string{"user"})(getJobArray)).Methods(http.MethodGet)
+	rUser.Handle(jobArrayPath+"/cancel",
+		auth.Jwt(authSecret, []string{"user"})(postCancelJobArray)).Methods(http.MethodPost)
+
+	rUser.Handle("/org", auth.Jwt(authSecret, []string{"user"})(getOrgs)).Methods(http.MethodGet)
+	rUser.Handle("/org", auth.Jwt(authSecret, []string{"user"})(postOrg)).Methods(http.MethodPost)
+	orgPath := fmt.Sprintf("/org/{orgID:%s}", uuidRegexpMux)
+	rUser.Handle(orgPath+"/member", auth.Jwt(authSecret, []string{"user"})(getOrgMembers)).Methods(http.MethodGet)
+	rUser.Handle(orgPath+"/member", auth.Jwt(authSecret, []string{"user"})(postOrgMember)).Methods(http.MethodPost)
+	orgMemberPath := fmt.Sprintf("%s/member/{aID:%s}", orgPath, uuidRegexpMux)
+	rUser.Handle(orgMemberPath, auth.Jwt(authSecret, []string{"user"})(patchOrgMember)).Methods(http.MethodPatch)
+	rUser.Handle(orgMemberPath, auth.Jwt(authSecret, []string{"user"})(deleteOrgMember)).Methods(http.MethodDelete)
+	rUser.Handle(orgPath+"/project", auth.Jwt(authSecret, []string{"user"})(getOrgProjects)).Methods(http.MethodGet)
+	rUser.Handle(orgPath+"/usage", auth.Jwt(authSecret, []string{"user"})(getOrgUsage)).Methods(http.MethodGet)
+	rUser.Handle(orgPath+"/usage/report", auth.Jwt(authSecret, []string{"user"})(getOrgUsageReport)).Methods(http.MethodGet)
+	rUser.Handle(orgPath+"/stripe/token", auth.Jwt(authSecret, []string{"user"})(postOrgStripeToken)).Methods(http.MethodPost)
 
 	rUser.Handle("/stripe-id", auth.Jwt(authSecret, []string{})(getStripeAccountID)).Methods(http.MethodGet)
 	rUser.Handle("/confirm-stripe", auth.Jwt(authSecret, []string{})(postStripeConfirmAccount)).Methods(http.MethodPost)
@@ -114,10 +202,28 @@ func main() {
 	rUser.Handle("/stripe/token", auth.Jwt(authSecret, []string{})(postStripeCustomerToken)).Methods(http.MethodPost)
 	rUser.Handle("/stripe/last4", auth.Jwt(authSecret, []string{})(getStripeCustomerLast4)).Methods(http.MethodGet)
 
+	rAdmin := rUser.PathPrefix("/admin").Subrouter()
+	rAdmin.Use(auth.Jwt(authSecret, []string{"admin"}))
+	adminJobPath := fmt.Sprintf("/job/{jID:%s}", uuidRegexpMux)
+	rAdmin.Handle(adminJobPath+"/adjustments", getJobAdjustments).Methods(http.MethodGet)
+	rAdmin.Handle(adminJobPath+"/refund", postJobRefund).Methods(http.MethodPost)
+	rAdmin.Handle(adminJobPath+"/reverse-transfer", postJobReverseTransfer).Methods(http.MethodPost)
+	rAdmin.Handle(fmt.Sprintf("/account/{aID:%s}/credit", uuidRegexpMux), postAccountCredit).Methods(http.MethodPost)
+
 	jobPathPrefix := fmt.Sprintf("/project/{project:%s}/job", projectRegexpMux)
 	rUserAuth := rUser.PathPrefix(jobPathPrefix).Subrouter()
 	rUserAuth.Use(auth.Jwt(authSecret, []string{"user"}))
 	rUserAuth.Handle("", auth.UserActive(postJob)).Methods(http.MethodPost)
+	patchJobPath := fmt.Sprintf("/{jID:%s}", uuidRegexpMux)
+	rUserAuth.Handle(patchJobPath, auth.UserJobMiddleware(patchJob)).Methods(http.MethodPatch)
+	postRerunPath := fmt.Sprintf("/{jID:%s}/rerun", uuidRegexpMux)
+	rUserAuth.Handle(postRerunPath,
+		auth.UserActive(auth.UserJobMiddleware(postRerunJob))).Methods(http.MethodPost)
+	postJobArrayPath := fmt.Sprintf("/{jID:%s}/array", uuidRegexpMux)
+	rUserAuth.Handle(postJobArrayPath,
+		auth.UserActive(auth.UserJobMiddleware(postJobArray))).Methods(http.MethodPost)
+	getJobBillingPath := fmt.Sprintf("/{jID:%s}/billing", uuidRegexpMux)
+	rUserAuth.Handle(getJobBillingPath, auth.UserJobMiddleware(getJobBilling)).Methods(http.MethodGet)
 	postCancelPath := fmt.Sprintf("/{jID:%s}/cancel", uuidRegexpMux)
 	rUserAuth.Handle(postCancelPath,
 		auth.JobActive(auth.UserJobMiddleware(postCancelJob))).Methods(http.MethodPost)
@@ -127,6 +233,9 @@ func main() {
 			"https://www.emrys.io",
 			"http://localhost:8080",
 		},
+		AllowedMethods: []string{
+			http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete, http.MethodHead,
+		},
 		AllowedHeaders: []string{
 			"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization",
 		},
@@ -151,6 +260,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 	<-stop
+	close(done)
 	if err := server.Shutdown(ctx); err != nil {
 		log.Sugar.Errorf("shutting server down: %v", err)
 	}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	stripe "github.com/stripe/stripe-go"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/email"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// processStripeEvent handles a verified stripe webhook event once, skipping redeliveries of
+// events already processed. Errors are returned as 500s so stripe retries the event
+func processStripeEvent(r *http.Request, event *stripe.Event) *app.Error {
+	ev := &db.StripeEvent{
+		ID:      event.ID,
+		Type:    event.Type,
+		Created: time.Unix(event.Created, 0),
+	}
+	switch event.Type {
+	case "invoice.payment_failed":
+		ev.CustomerID, ev.Suspended = event.GetObjectValue("customer"), true
+	case "invoice.paid":
+		ev.CustomerID, ev.Suspended = event.GetObjectValue("customer"), false
+	case "customer.subscription.updated":
+		switch event.GetObjectValue("status") {
+		case "past_due", "unpaid":
+			ev.CustomerID, ev.Suspended = event.GetObjectValue("customer"), true
+		case "active":
+			ev.CustomerID, ev.Suspended = event.GetObjectValue("customer"), false
+		}
+	case "customer.subscription.deleted":
+		// jobs can't be billed without a subscription; adding payment info again
+		// re-subscribes the account and reactivates it
+		ev.SubscriptionID = event.GetObjectValue("id")
+		ev.CustomerID, ev.Suspended = event.GetObjectValue("customer"), true
+	case "account.updated":
+		ev.StripeAccountID = event.GetObjectValue("id")
+		ev.ChargesEnabled = event.GetObjectValue("charges_enabled") == "true"
+		ev.PayoutsEnabled = event.GetObjectValue("payouts_enabled") == "true"
+	}
+
+	applied, changed, err := db.ApplyStripeEvent(r, ev)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	} else if !applied {
+		return nil
+	}
+
+	if changed {
+		log.Sugar.Infow("account payment suspension changed",
+			"method", r.Method,
+			"url", r.URL,
+			"event", event.Type,
+			"customerID", ev.CustomerID,
+			"suspended", ev.Suspended,
+		)
+	}
+	switch event.Type {
+	case "account.updated":
+		if !ev.PayoutsEnabled {
+			reason := ""
+			if requirements, ok := event.Data.Object["requirements"].(map[string]interface{}); ok {
+				reason, _ = requirements["disabled_reason"].(string)
+			}
+			log.Sugar.Infow("stripe account payouts disabled",
+				"method", r.Method,
+				"url", r.URL,
+				"stripeAccountID", ev.StripeAccountID,
+				"reason", reason,
+			)
+		}
+	case "payout.paid":
+		log.Sugar.Infow("payout.paid",
+			"amt", event.GetObjectValue("amount"),
+			"tx", event.GetObjectValue("balance_transaction"),
+			"dest", event.GetObjectValue("destination"),
+			"status", event.GetObjectValue("status"),
+		)
+	case "payout.failed":
+		amt := event.GetObjectValue("amount")
+		dest := event.GetObjectValue("destination")
+		log.Sugar.Errorw("payout.failed",
+			"amt", amt,
+			"tx", event.GetObjectValue("balance_transaction"),
+			"dest", dest,
+			"status", event.GetObjectValue("status"),
+		)
+		if err := email.SendPayoutFailed(dest, amt); err != nil {
+			log.Sugar.Errorw("error sending payout-failed email to support",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+			)
+		}
+	}
+	return nil
+}
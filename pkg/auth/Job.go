@@ -49,7 +49,9 @@ func JobActive(h http.Handler) http.Handler {
 	})
 }
 
-// UserJobMiddleware authorizes an authenticated user for the requested job
+// UserJobMiddleware authorizes an authenticated user for the requested job. Job creators
+// have full access; teammates in the job's organization may read it (GET) as viewers or
+// above and act on it as admins or above
 func UserJobMiddleware(h http.Handler) http.Handler {
 	return app.Handler(func(w http.ResponseWriter, r *http.Request) *app.Error {
 		vars := mux.Vars(r)
@@ -76,9 +78,9 @@ func UserJobMiddleware(h http.Handler) http.Handler {
 			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 		}
 
-		dbuUUID, err := db.GetJobOwner(r, jUUID)
+		creator, role, err := db.GetJobAccess(r, jUUID, uUUID)
 		if err != nil {
-			log.Sugar.Errorw("retrieving job owner",
+			log.Sugar.Errorw("retrieving job access",
 				"method", r.Method,
 				"url", r.URL,
 				"err", err.Error(),
@@ -86,15 +88,21 @@ func UserJobMiddleware(h http.Handler) http.Handler {
 			)
 			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 		}
-		if !uuid.Equal(uUUID, dbuUUID) {
-			log.Sugar.Errorf("User %v: jwt claims subject does not own job %v (winner: %v)", uUUID, jUUID.String(), dbuUUID)
+		minRole := db.OrgRoleAdmin
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			minRole = db.OrgRoleViewer
+		}
+		if !creator && !role.AtLeast(minRole) {
+			log.Sugar.Errorf("User %v: jwt claims subject may not %s job %v (org role: %q)", uUUID, r.Method, jUUID.String(), role)
 			return &app.Error{Code: http.StatusUnauthorized, Message: "unauthorized jwt"}
 		}
 
-		log.Sugar.Infow("valid user, owns job",
+		log.Sugar.Infow("valid user, may access job",
 			"method", r.Method,
 			"url", r.URL,
 			"jID", jUUID,
+			"creator", creator,
+			"role", role,
 		)
 		h.ServeHTTP(w, r)
 		return nil
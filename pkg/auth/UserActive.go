@@ -8,7 +8,8 @@ import (
 	"net/http"
 )
 
-// UserActive checks if the user is not suspended
+// UserActive checks if the user is not suspended, and may post jobs: a failed payment stops them
+// until their payment info is updated
 func UserActive(h http.Handler) http.Handler {
 	return app.Handler(func(w http.ResponseWriter, r *http.Request) *app.Error {
 		uID := r.Header.Get("X-Jwt-Claims-Subject")
@@ -32,6 +33,16 @@ func UserActive(h http.Handler) http.Handler {
 			)
 			return &app.Error{Code: http.StatusUnauthorized, Message: "user is suspended"}
 		}
+		if suspended, err := db.GetAccountPaymentSuspended(r, uUUID); err != nil {
+			return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // err already logged
+		} else if suspended {
+			log.Sugar.Infow("user is payment suspended",
+				"method", r.Method,
+				"url", r.URL,
+				"uID", uID,
+			)
+			return &app.Error{Code: http.StatusPaymentRequired, Message: "your last payment failed; please update your payment info"}
+		}
 
 		log.Sugar.Infof("user is active")
 		h.ServeHTTP(w, r)
@@ -6,19 +6,20 @@ import (
 	"github.com/wminshew/emrysserver/pkg/log"
 )
 
-// SetAccountCredit sets the account aUUID's credit
-func SetAccountCredit(aUUID uuid.UUID, newCredit int64) error {
+// AddJobOutputBytes adds n bytes to the stored output size of job jUUID
+func AddJobOutputBytes(jUUID uuid.UUID, n int64) error {
 	sqlStmt := `
-	UPDATE accounts
-	SET credit = $2
+	UPDATE jobs
+	SET output_bytes = output_bytes + $2
 	WHERE uuid = $1
 	`
-	if _, err := db.Exec(sqlStmt, aUUID, newCredit); err != nil {
-		message := "error querying account credit"
+	if _, err := db.Exec(sqlStmt, jUUID, n); err != nil {
+		message := "error updating jobs output_bytes"
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			log.Sugar.Errorw(message,
 				"err", err.Error(),
+				"jID", jUUID,
 				"pq_sev", pqErr.Severity,
 				"pq_code", pqErr.Code,
 				"pq_msg", pqErr.Message,
@@ -27,9 +28,11 @@ func SetAccountCredit(aUUID uuid.UUID, newCredit int64) error {
 		} else {
 			log.Sugar.Errorw(message,
 				"err", err.Error(),
+				"jID", jUUID,
 			)
 		}
 		return err
 	}
+
 	return nil
 }
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// StripeEvent is a stripe webhook event and the account changes it calls for. Fields left empty
+// call for no change
+type StripeEvent struct {
+	ID      string
+	Type    string
+	Created time.Time
+	// CustomerID's account is stopped from posting jobs for a failed payment, or let again, per Suspended
+	CustomerID string
+	Suspended  bool
+	// SubscriptionID is unset from the account or org it belongs to, so a new one is created
+	// when payment info is next added
+	SubscriptionID string
+	// StripeAccountID's connected account capabilities are set to ChargesEnabled and PayoutsEnabled
+	StripeAccountID string
+	ChargesEnabled  bool
+	PayoutsEnabled  bool
+}
+
+// ApplyStripeEvent records stripe event ev as processed and makes its account changes in one
+// transaction, so each event is applied once however often it's delivered. Suspension and
+// capability changes are skipped if a later event has already been applied, as stripe doesn't
+// deliver events in order. Returns whether ev was applied (false if it already had been) and
+// whether its customer's payment suspension changed. Org customers are left alone
+func ApplyStripeEvent(r *http.Request, ev *StripeEvent) (bool, bool, error) {
+	var applied, changed bool
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
+		INSERT INTO stripe_events (id, type)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING
+		`
+		res, err := tx.Exec(sqlStmt, ev.ID, ev.Type)
+		if err != nil {
+			return "error inserting stripe event", err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return "error getting rows affected", err
+		} else if n == 0 {
+			return "", tx.Commit()
+		}
+		applied = true
+
+		if ev.SubscriptionID != "" {
+			sqlStmt = `
+		WITH a AS (
+			UPDATE accounts
+			SET stripe_subscription_id = NULL
+			WHERE stripe_subscription_id = $1
+		)
+		UPDATE orgs
+		SET stripe_subscription_id = NULL
+		WHERE stripe_subscription_id = $1
+		`
+			if _, err := tx.Exec(sqlStmt, ev.SubscriptionID); err != nil {
+				return "error clearing stripe subscription ID", err
+			}
+		}
+
+		if ev.CustomerID != "" {
+			sqlStmt = `
+		UPDATE accounts
+		SET payment_suspended = $2
+		WHERE stripe_customer_id = $1 AND
+			payment_suspended <> $2 AND
+			(payment_suspension_at IS NULL OR payment_suspension_at <= $3)
+		`
+			res, err := tx.Exec(sqlStmt, ev.CustomerID, ev.Suspended, ev.Created)
+			if err != nil {
+				return "error updating account payment suspended", err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return "error getting rows affected", err
+			}
+			changed = n > 0
+
+			sqlStmt = `
+		UPDATE accounts
+		SET payment_suspension_at = $2
+		WHERE stripe_customer_id = $1 AND
+			(payment_suspension_at IS NULL OR payment_suspension_at <= $2)
+		`
+			if _, err := tx.Exec(sqlStmt, ev.CustomerID, ev.Created); err != nil {
+				return "error updating account payment_suspension_at", err
+			}
+		}
+
+		if ev.StripeAccountID != "" {
+			sqlStmt = `
+		UPDATE accounts
+		SET stripe_charges_enabled = $2,
+		stripe_payouts_enabled = $3,
+		stripe_capabilities_at = $4
+		WHERE stripe_account_id = $1 AND
+			(stripe_capabilities_at IS NULL OR stripe_capabilities_at <= $4)
+		`
+			if _, err := tx.Exec(sqlStmt, ev.StripeAccountID, ev.ChargesEnabled, ev.PayoutsEnabled, ev.Created); err != nil {
+				return "error updating account stripe capabilities", err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"eventID", ev.ID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"eventID", ev.ID,
+			)
+		}
+		return false, false, err
+	}
+	return applied, changed, nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+// ClaimPaymentTask claims the next due payment task of one of kinds for lease, skipping tasks
+// other workers hold. Tasks whose lease ran out without finishing are due again. Returns
+// sql.ErrNoRows if there is none
+func ClaimPaymentTask(kinds []PaymentTaskKind, lease time.Duration) (*PaymentTask, error) {
+	strKinds := make([]string, len(kinds))
+	for i, k := range kinds {
+		strKinds[i] = string(k)
+	}
+	t := &PaymentTask{}
+	sqlStmt := `
+	UPDATE payment_tasks
+	SET state = 'running',
+		attempts = attempts + 1,
+		locked_until = NOW() + $2 * INTERVAL '1 second',
+		updated_at = NOW()
+	WHERE id = (
+		SELECT id
+		FROM payment_tasks
+		WHERE kind = ANY($1) AND
+			((state = 'pending' AND run_at <= NOW()) OR
+			(state = 'running' AND locked_until < NOW()))
+		ORDER BY run_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, kind, job_uuid, attempts
+	`
+	if err := db.QueryRow(sqlStmt, pq.Array(strKinds), lease.Seconds()).Scan(&t.ID, &t.Kind, &t.Job, &t.Attempts); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		message := "error claiming payment task"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+			)
+		}
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ClearProjectBusy lets jobs be posted to user uUUID's project again after an op failed
+func ClearProjectBusy(r *http.Request, uUUID uuid.UUID, project string) error {
+	sqlStmt := `
+	UPDATE projects
+	SET busy = NULL
+	WHERE (name, user_uuid) = ($1, $2) AND
+		deleted_at IS NULL AND
+		org_uuid IS NULL
+	`
+	if _, err := db.Exec(sqlStmt, project, uUUID); err != nil {
+		message := "error clearing project busy"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+			)
+		}
+		return err
+	}
+	return nil
+}
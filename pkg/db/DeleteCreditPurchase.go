@@ -0,0 +1,43 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// DeleteCreditPurchase deletes credit purchase cpUUID if it's still pending, so it no longer
+// holds off its account's auto-recharge
+func DeleteCreditPurchase(r *http.Request, cpUUID uuid.UUID) error {
+	sqlStmt := `
+	DELETE FROM credit_purchases
+	WHERE uuid = $1 AND
+		finalized_at IS NULL
+	`
+	if _, err := db.Exec(sqlStmt, cpUUID); err != nil {
+		message := "error deleting pending credit purchase"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"cpID", cpUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"cpID", cpUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// DeleteOrgMember removes account aUUID from organization oUUID. Returns sql.ErrNoRows
+// if it isn't a member and ErrOrgLastOwner if the organization would be left without an owner
+func DeleteOrgMember(r *http.Request, oUUID, aUUID uuid.UUID) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		var current OrgRole
+		sqlStmt := `
+	SELECT role
+	FROM org_members
+	WHERE (org_uuid, account_uuid) = ($1, $2)
+	FOR UPDATE
+	`
+		if err := tx.QueryRow(sqlStmt, oUUID, aUUID).Scan(&current); err != nil {
+			return "error querying for org member", err
+		}
+
+		if current == OrgRoleOwner {
+			var owners int64
+			sqlStmt = `
+	SELECT COUNT(*)
+	FROM (
+		SELECT account_uuid
+		FROM org_members
+		WHERE org_uuid = $1 AND
+			role = 'owner'
+		FOR UPDATE
+	) owners
+	`
+			if err := tx.QueryRow(sqlStmt, oUUID).Scan(&owners); err != nil {
+				return "error querying for org owners", err
+			} else if owners <= 1 {
+				return "org would have no owner", ErrOrgLastOwner
+			}
+		}
+
+		sqlStmt = `
+	DELETE FROM org_members
+	WHERE (org_uuid, account_uuid) = ($1, $2)
+	`
+		if _, err := tx.Exec(sqlStmt, oUUID, aUUID); err != nil {
+			return "error deleting org member", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		if err == sql.ErrNoRows || err == ErrOrgLastOwner {
+			return err
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"aID", aUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// DeletePaymentAdjustment deletes adjustment adjUUID if it's still pending, freeing its amount
+// to be refunded or reversed again
+func DeletePaymentAdjustment(r *http.Request, adjUUID uuid.UUID) error {
+	sqlStmt := `
+	DELETE FROM payment_adjustments
+	WHERE uuid = $1 AND
+		finalized_at IS NULL
+	`
+	if _, err := db.Exec(sqlStmt, adjUUID); err != nil {
+		message := "error deleting pending payment adjustment"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adjUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adjUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
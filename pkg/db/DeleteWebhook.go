@@ -0,0 +1,54 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// DeleteWebhook deletes account aUUID's webhook whUUID. Returns sql.ErrNoRows if
+// the account has no such webhook
+func DeleteWebhook(r *http.Request, aUUID, whUUID uuid.UUID) error {
+	sqlStmt := `
+	UPDATE webhooks
+	SET deleted_at = NOW()
+	WHERE uuid = $1 AND
+		account_uuid = $2 AND
+		deleted_at IS NULL
+	`
+	res, err := db.Exec(sqlStmt, whUUID, aUUID)
+	if err != nil {
+		message := "error deleting webhook"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
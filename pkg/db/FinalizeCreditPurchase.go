@@ -0,0 +1,92 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// FinalizeCreditPurchase records that pending credit purchase cp was charged by stripe charge
+// cp.StripeID, adds its credit to the account and records ledger transaction lt, atomically.
+// Finalizing it again is a no-op
+func FinalizeCreditPurchase(r *http.Request, cp *CreditPurchase, lt *LedgerTxn) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
+		UPDATE credit_purchases
+		SET stripe_id = $2,
+			finalized_at = NOW()
+		WHERE uuid = $1 AND
+			finalized_at IS NULL
+		`
+		result, err := tx.Exec(sqlStmt, cp.UUID, cp.StripeID)
+		if err != nil {
+			return "error updating credit purchase finalized_at", err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return "error getting rows affected", err
+		} else if n == 0 {
+			if err := tx.Commit(); err != nil {
+				return errCommitTx, err
+			}
+			return "", nil
+		}
+
+		sqlStmt = `
+		UPDATE accounts
+		SET credit = credit + $2
+		WHERE uuid = $1
+		`
+		if _, err := tx.Exec(sqlStmt, cp.Account, cp.Credit); err != nil {
+			return "error updating account credit", err
+		}
+
+		if err := insertLedgerTxn(tx, lt); err != nil {
+			return "error inserting ledger txn", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"cpID", cp.UUID,
+				"aID", cp.Account,
+				"jID", cp.TriggerJob,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"cpID", cp.UUID,
+				"aID", cp.Account,
+				"jID", cp.TriggerJob,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// FinalizePaymentAdjustment records that pending adjustment adj was made by stripe object
+// adj.StripeID, adds creditDelta (cents) to account adj.Account's credit and records ledger
+// transaction lt, atomically. Finalizing it again is a no-op
+func FinalizePaymentAdjustment(r *http.Request, adj *PaymentAdjustment, creditDelta int64, lt *LedgerTxn) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
+		UPDATE payment_adjustments
+		SET stripe_id = NULLIF($2, ''),
+			finalized_at = NOW()
+		WHERE uuid = $1 AND
+			finalized_at IS NULL
+		`
+		result, err := tx.Exec(sqlStmt, adj.UUID, adj.StripeID)
+		if err != nil {
+			return "error updating payment adjustment finalized_at", err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return "error getting rows affected", err
+		} else if n == 0 {
+			if err := tx.Commit(); err != nil {
+				return errCommitTx, err
+			}
+			return "", nil
+		}
+
+		if creditDelta != 0 {
+			sqlStmt = `
+		UPDATE accounts
+		SET credit = credit + $2
+		WHERE uuid = $1
+		`
+			if _, err := tx.Exec(sqlStmt, adj.Account, creditDelta); err != nil {
+				return "error updating account credit", err
+			}
+		}
+
+		if err := insertLedgerTxn(tx, lt); err != nil {
+			return "error inserting ledger txn", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adj.UUID,
+				"jID", adj.Job,
+				"aID", adj.Account,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adj.UUID,
+				"jID", adj.Job,
+				"aID", adj.Account,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetAccountAdmin returns whether account aUUID is a platform admin
+func GetAccountAdmin(r *http.Request, aUUID uuid.UUID) (bool, error) {
+	var admin bool
+	sqlStmt := `
+	SELECT admin
+	FROM accounts
+	WHERE uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, aUUID).Scan(&admin); err != nil {
+		message := "error querying for account admin"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return false, err
+	}
+	return admin, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetAccountCreditHistory returns rows holding the id, created_at, kind, job (if any) and amount
+// (cents, positive when credit was added) of account aUUID's credit ledger entries, newest first,
+// at most limit of them and only those with id below beforeID if it's positive
+func GetAccountCreditHistory(r *http.Request, aUUID uuid.UUID, beforeID int64, limit int) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT id, created_at, kind, job_uuid, -amount
+	FROM ledger_entries
+	WHERE account = $1 AND
+		owner_uuid = $2 AND
+		($3::bigint <= 0 OR id < $3)
+	ORDER BY id DESC
+	LIMIT $4
+	`
+	rows, err := db.Query(sqlStmt, LedgerUserCredit, aUUID, beforeID, limit)
+	if err != nil {
+		message := "error querying for account credit history"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -7,22 +7,52 @@ import (
 	"github.com/wminshew/emrysserver/pkg/log"
 )
 
-// GetAccountJobHistory returns rows holding jobs related to account aUUID
-func GetAccountJobHistory(aUUID uuid.UUID) (*sql.Rows, error) {
+// GetAccountJobHistory returns rows holding the JobHistoryRow columns followed by the project, name,
+// tags and metadata of the jobs account aUUID created, filtered and paged by f
+func GetAccountJobHistory(aUUID uuid.UUID, f *JobHistoryFilter) (*sql.Rows, error) {
+	metadata := sql.NullString{}
+	if len(f.Metadata) > 0 {
+		b, err := metadataJSON(f.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		metadata = sql.NullString{String: string(b), Valid: true}
+	}
 	sqlStmt := `
-	SELECT j.uuid
-	FROM jobs j, projects proj, users u
-	WHERE u.uuid = $1 AND
-		proj.user_uuid = u.uuid AND
-		j.project_uuid = proj.uuid
+	SELECT j.uuid, j.state, j.notebook, j.created_at,
+		j.completed_at, j.canceled_at, j.failed_at, j.rate, b.gpu,
+		s.auction_completed, s.data_downloaded, s.image_downloaded, s.output_data_posted,
+		j.last_heartbeat_at, proj.name, j.name, j.tags, j.metadata
+	FROM jobs j
+	INNER JOIN projects proj ON (proj.uuid = j.project_uuid)
+	INNER JOIN statuses s ON (s.job_uuid = j.uuid)
+	LEFT JOIN bids b ON (b.uuid = j.win_bid_uuid)
+	WHERE j.creator_uuid = $1 AND
+		($2 = '' OR proj.name = $2) AND
+		($3 = '' OR j.state = $3) AND
+		($4::timestamptz IS NULL OR j.created_at >= $4) AND
+		($5::timestamptz IS NULL OR j.created_at < $5) AND
+		($6::timestamptz IS NULL OR (j.created_at, j.uuid) < ($6, $7)) AND
+		($9 = '' OR strpos(lower(j.name), lower($9)) > 0) AND
+		($10::text[] IS NULL OR j.tags @> $10) AND
+		($11::jsonb IS NULL OR j.metadata @> $11) AND
+		($12 = '' OR
+			strpos(lower(j.name), lower($12)) > 0 OR
+			$12 = ANY(j.tags) OR
+			EXISTS (SELECT 1 FROM jsonb_each_text(j.metadata) m WHERE strpos(lower(m.value), lower($12)) > 0))
+	ORDER BY j.created_at DESC, j.uuid DESC
+	LIMIT $8
 	`
-	rows, err := db.Query(sqlStmt, aUUID)
+	rows, err := db.Query(sqlStmt, aUUID, f.Project, f.State, nullTime(f.Since), nullTime(f.Until),
+		nullTime(f.AfterCreatedAt), f.AfterUUID, f.Limit,
+		f.Name, pq.Array(f.Tags), metadata, f.Query)
 	if err != nil {
 		message := "error querying for account job history"
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			log.Sugar.Errorw(message,
 				"err", err.Error(),
+				"aID", aUUID,
 				"pq_sev", pqErr.Severity,
 				"pq_code", pqErr.Code,
 				"pq_msg", pqErr.Message,
@@ -31,6 +61,7 @@ func GetAccountJobHistory(aUUID uuid.UUID) (*sql.Rows, error) {
 		} else {
 			log.Sugar.Errorw(message,
 				"err", err.Error(),
+				"aID", aUUID,
 			)
 		}
 	}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetAccountNotifyEmail returns whether account aUUID has opted into job notification emails
+func GetAccountNotifyEmail(r *http.Request, aUUID uuid.UUID) (bool, error) {
+	var notify bool
+	sqlStmt := `
+	SELECT notify_email
+	FROM accounts
+	WHERE uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, aUUID).Scan(&notify); err != nil {
+		message := "error querying for account notify_email"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return false, err
+	}
+
+	return notify, nil
+}
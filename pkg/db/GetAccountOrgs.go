@@ -0,0 +1,41 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetAccountOrgs returns rows holding the uuid, name and account aUUID's role of each
+// organization it belongs to
+func GetAccountOrgs(aUUID uuid.UUID) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT o.uuid, o.name, m.role
+	FROM orgs o
+	INNER JOIN org_members m ON (m.org_uuid = o.uuid)
+	WHERE m.account_uuid = $1
+	ORDER BY o.name
+	`
+	rows, err := db.Query(sqlStmt, aUUID)
+	if err != nil {
+		message := "error querying for account orgs"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+	}
+	return rows, err
+}
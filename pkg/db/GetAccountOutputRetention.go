@@ -0,0 +1,54 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetAccountOutputRetention gets the account aUUID's output retention days and max bytes (0 if unlimited)
+func GetAccountOutputRetention(r *http.Request, aUUID uuid.UUID) (int64, int64, error) {
+	days := sql.NullInt64{}
+	maxBytes := sql.NullInt64{}
+	sqlStmt := `
+	SELECT a.output_retention_days, a.output_retention_bytes
+	FROM accounts a
+	WHERE a.uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, aUUID).Scan(&days, &maxBytes); err != nil {
+		message := "error querying account output retention"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return 0, 0, err
+	}
+
+	var daysReturn int64
+	if days.Valid {
+		daysReturn = days.Int64
+	}
+	var maxBytesReturn int64
+	if maxBytes.Valid {
+		maxBytesReturn = maxBytes.Int64
+	}
+	return daysReturn, maxBytesReturn, nil
+}
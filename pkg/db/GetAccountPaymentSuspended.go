@@ -0,0 +1,43 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetAccountPaymentSuspended returns whether account is stopped from posting jobs for a failed payment
+func GetAccountPaymentSuspended(r *http.Request, aUUID uuid.UUID) (bool, error) {
+	var suspended bool
+	sqlStmt := `
+	SELECT payment_suspended
+	FROM accounts
+	WHERE uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, aUUID).Scan(&suspended); err != nil {
+		message := "error querying for account payment suspended"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return false, err
+	}
+	return suspended, nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetAccountWebhooks returns rows holding the uuid, url and created_at of account aUUID's webhooks
+func GetAccountWebhooks(aUUID uuid.UUID) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT uuid, url, created_at
+	FROM webhooks
+	WHERE account_uuid = $1 AND
+		deleted_at IS NULL
+	ORDER BY created_at
+	`
+	rows, err := db.Query(sqlStmt, aUUID)
+	if err != nil {
+		message := "error querying for account webhooks"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetAutoRecharge returns the credit pack account aUUID buys automatically ("" if auto-recharge
+// is off) and the credit (cents) it's bought below
+func GetAutoRecharge(r *http.Request, aUUID uuid.UUID) (string, int64, error) {
+	var pack string
+	var threshold int64
+	sqlStmt := `
+	SELECT COALESCE(auto_recharge_pack, ''), COALESCE(auto_recharge_threshold, 0)
+	FROM accounts
+	WHERE uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, aUUID).Scan(&pack, &threshold); err != nil {
+		message := "error querying for account auto-recharge"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return "", 0, err
+	}
+	return pack, threshold, nil
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetExpiredOutputs returns rows holding inactive jobs whose outputs have outlived their
+// owner's retention policy, either by age or by falling outside the owner's max total bytes
+// (newest jobs are kept first)
+func GetExpiredOutputs() (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT e.uuid
+	FROM (
+		SELECT j.uuid,
+			a.output_retention_days AS days,
+			a.output_retention_bytes AS max_bytes,
+			COALESCE(j.completed_at, j.canceled_at, j.failed_at) AS ended_at,
+			SUM(j.output_bytes) OVER (
+				PARTITION BY j.creator_uuid
+				ORDER BY j.created_at DESC
+			) AS cum_bytes
+		FROM jobs j
+		INNER JOIN accounts a ON (a.uuid = j.creator_uuid)
+		INNER JOIN statuses s ON (s.job_uuid = j.uuid)
+		WHERE j.state IN ('finished', 'canceled', 'failed') AND
+			s.output_deleted IS NULL
+	) e
+	WHERE e.ended_at IS NOT NULL AND (
+		(e.days IS NOT NULL AND e.ended_at < NOW() - e.days * INTERVAL '1 day') OR
+		(e.max_bytes IS NOT NULL AND e.cum_bytes > e.max_bytes)
+	)
+	`
+	rows, err := db.Query(sqlStmt)
+	if err != nil {
+		message := "error querying for expired outputs"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+// FeeSchedule is the platform's take on a job: Percent (0-1) of the job's amount plus Fixed cents
+type FeeSchedule struct {
+	ID      int64
+	Percent float64
+	Fixed   int64
+}
+
+// GetFeeSchedule returns the fee schedule in effect at time at for jobs on gpu paid for by an
+// account or org of fee tier. Schedules for a specific tier win over ones for any tier, then
+// schedules for a specific gpu over ones for any gpu, then the most recently effective. Returns
+// sql.ErrNoRows if no schedule applies
+func GetFeeSchedule(gpu, tier string, at time.Time) (*FeeSchedule, error) {
+	fs := &FeeSchedule{}
+	sqlStmt := `
+	SELECT id, percent, fixed
+	FROM fee_schedules
+	WHERE (gpu = $1 OR gpu IS NULL) AND
+		(tier = $2 OR tier IS NULL) AND
+		effective_at <= $3
+	ORDER BY tier IS NULL, gpu IS NULL, effective_at DESC
+	LIMIT 1
+	`
+	if err := db.QueryRow(sqlStmt, gpu, tier, at).Scan(&fs.ID, &fs.Percent, &fs.Fixed); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		message := "error querying for fee schedule"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"gpu", gpu,
+				"tier", tier,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"gpu", gpu,
+				"tier", tier,
+			)
+		}
+		return nil, err
+	}
+	return fs, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetJobAccess returns whether user uUUID created job jUUID and, for org projects, its
+// role in the job's organization. Creators of org jobs lose access when they leave the org
+func GetJobAccess(r *http.Request, jUUID, uUUID uuid.UUID) (bool, OrgRole, error) {
+	var creator bool
+	var role sql.NullString
+	sqlStmt := `
+	SELECT j.creator_uuid = $2 AND (p.org_uuid IS NULL OR m.role IS NOT NULL), m.role
+	FROM jobs j
+	INNER JOIN projects p ON (p.uuid = j.project_uuid)
+	LEFT JOIN org_members m ON (m.org_uuid = p.org_uuid AND m.account_uuid = $2)
+	WHERE j.uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID, uUUID).Scan(&creator, &role); err != nil {
+		message := "error querying for job access"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"uID", uUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"uID", uUUID,
+			)
+		}
+		return false, "", err
+	}
+	return creator, OrgRole(role.String), nil
+}
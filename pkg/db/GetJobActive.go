@@ -6,16 +6,25 @@ import (
 	"github.com/wminshew/emrysserver/pkg/log"
 )
 
-// GetJobActive returns whether job is active
+// GetJobActive returns whether job is active (i.e. hasn't finished, been canceled or failed)
 func GetJobActive(jUUID uuid.UUID) (bool, error) {
-	var active bool
+	state, err := GetJobState(jUUID)
+	if err != nil {
+		return false, err // already logged
+	}
+	return !state.Terminal(), nil
+}
+
+// GetJobState returns job jUUID's current state
+func GetJobState(jUUID uuid.UUID) (JobState, error) {
+	var state JobState
 	sqlStmt := `
-	SELECT active
+	SELECT state
 	FROM jobs
 	WHERE uuid = $1
 	`
-	if err := db.QueryRow(sqlStmt, jUUID).Scan(&active); err != nil {
-		message := "error querying for jobs.active"
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&state); err != nil {
+		message := "error querying for jobs.state"
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			log.Sugar.Errorw(message,
@@ -32,7 +41,7 @@ func GetJobActive(jUUID uuid.UUID) (bool, error) {
 				"jID", jUUID,
 			)
 		}
-		return false, err
+		return "", err
 	}
-	return active, nil
+	return state, nil
 }
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetJobAdjustments returns rows holding the uuid, kind, account, amount, method, stripe id,
+// reason, admin, created_at and whether it's pending of job jUUID's payment adjustments, oldest first
+func GetJobAdjustments(r *http.Request, jUUID uuid.UUID) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT uuid, kind, account_uuid, amount, method, COALESCE(stripe_id, ''), reason, admin_uuid, created_at,
+		finalized_at IS NULL
+	FROM payment_adjustments
+	WHERE job_uuid = $1
+	ORDER BY created_at
+	`
+	rows, err := db.Query(sqlStmt, jUUID)
+	if err != nil {
+		message := "error querying for job adjustments"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// GetJobArray returns the source job, created_at and canceled_at (zero if not canceled) of
+// user uUUID's job array arrUUID. Returns sql.ErrNoRows if the user has no such array
+func GetJobArray(r *http.Request, uUUID, arrUUID uuid.UUID) (uuid.UUID, time.Time, time.Time, error) {
+	jUUID := uuid.UUID{}
+	createdAt := time.Time{}
+	canceledAt := pq.NullTime{}
+	sqlStmt := `
+	SELECT source_job_uuid, created_at, canceled_at
+	FROM job_arrays
+	WHERE uuid = $1 AND
+		user_uuid = $2
+	`
+	if err := db.QueryRow(sqlStmt, arrUUID, uUUID).Scan(&jUUID, &createdAt, &canceledAt); err != nil {
+		if err != sql.ErrNoRows {
+			message := "error querying for job array"
+			pqErr, ok := err.(*pq.Error)
+			if ok {
+				log.Sugar.Errorw(message,
+					"method", r.Method,
+					"url", r.URL,
+					"err", err.Error(),
+					"arrID", arrUUID,
+					"pq_sev", pqErr.Severity,
+					"pq_code", pqErr.Code,
+					"pq_msg", pqErr.Message,
+					"pq_detail", pqErr.Detail,
+				)
+			} else {
+				log.Sugar.Errorw(message,
+					"method", r.Method,
+					"url", r.URL,
+					"err", err.Error(),
+					"arrID", arrUUID,
+				)
+			}
+		}
+		return uuid.Nil, time.Time{}, time.Time{}, err
+	}
+	return jUUID, createdAt, canceledAt.Time, nil
+}
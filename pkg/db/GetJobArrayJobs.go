@@ -0,0 +1,40 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetJobArrayJobs returns rows holding the uuid, array_index, array_args and state of job
+// array arrUUID's jobs, in index order
+func GetJobArrayJobs(arrUUID uuid.UUID) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT uuid, array_index, array_args, state
+	FROM jobs
+	WHERE array_uuid = $1
+	ORDER BY array_index
+	`
+	rows, err := db.Query(sqlStmt, arrUUID)
+	if err != nil {
+		message := "error querying for job array jobs"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"arrID", arrUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"arrID", arrUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetJobCheckpoints returns rows holding the name, size and posted_at of checkpoints for job jUUID
+func GetJobCheckpoints(jUUID uuid.UUID) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT name, size, posted_at
+	FROM checkpoints
+	WHERE job_uuid = $1
+	ORDER BY posted_at
+	`
+	rows, err := db.Query(sqlStmt, jUUID)
+	if err != nil {
+		message := "error querying for job checkpoints"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetJobDataFingerprint returns the fingerprint of the data set job jUUID synced, or an
+// empty string if none was recorded
+func GetJobDataFingerprint(r *http.Request, jUUID uuid.UUID) (string, error) {
+	fp := sql.NullString{}
+	sqlStmt := `
+	SELECT data_fingerprint
+	FROM jobs
+	WHERE uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&fp); err != nil {
+		message := "error querying for jobs.data_fingerprint"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return "", err
+	}
+	return fp.String, nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+// GetJobFeeBasis returns what job jUUID's fee schedule is chosen by: the gpu of its winning bid,
+// its payer's fee tier ("" if none) and when it was created
+func GetJobFeeBasis(jUUID uuid.UUID) (string, string, time.Time, error) {
+	var gpu, tier string
+	var createdAt time.Time
+	sqlStmt := `
+	SELECT b.gpu, COALESCE(o.fee_tier, a.fee_tier, ''), j.created_at
+	FROM jobs j
+	INNER JOIN bids b ON (b.uuid = j.win_bid_uuid)
+	INNER JOIN projects p ON (p.uuid = j.project_uuid)
+	INNER JOIN accounts a ON (a.uuid = j.creator_uuid)
+	LEFT JOIN orgs o ON (o.uuid = p.org_uuid)
+	WHERE j.uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&gpu, &tier, &createdAt); err != nil {
+		message := "error querying for job fee basis"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return "", "", time.Time{}, err
+	}
+	return gpu, tier, createdAt, nil
+}
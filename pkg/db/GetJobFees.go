@@ -0,0 +1,55 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// JobFees is how a job's amount (cents) is split between its miner and the platform.
+// FeeScheduleID is 0 if no fee schedule applied
+type JobFees struct {
+	FeeScheduleID int64
+	Amount        int64
+	Fee           int64
+	MinerAmount   int64
+}
+
+// GetJobFees returns job jUUID's recorded fees. Returns sql.ErrNoRows if none are recorded yet
+func GetJobFees(jUUID uuid.UUID) (*JobFees, error) {
+	jf := &JobFees{}
+	feeScheduleID := sql.NullInt64{}
+	sqlStmt := `
+	SELECT fee_schedule_id, amount, fee, miner_amount
+	FROM job_fees
+	WHERE job_uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&feeScheduleID, &jf.Amount, &jf.Fee, &jf.MinerAmount); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		message := "error querying for job fees"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return nil, err
+	}
+	if feeScheduleID.Valid {
+		jf.FeeScheduleID = feeScheduleID.Int64
+	}
+	return jf, nil
+}
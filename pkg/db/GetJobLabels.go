@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetJobLabels returns job jUUID's name, tags and metadata
+func GetJobLabels(r *http.Request, jUUID uuid.UUID) (*JobLabels, error) {
+	name := sql.NullString{}
+	tags := pq.StringArray{}
+	var metadata []byte
+	sqlStmt := `
+	SELECT name, tags, metadata
+	FROM jobs
+	WHERE uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&name, &tags, &metadata); err != nil {
+		message := "error querying for job labels"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return nil, err
+	}
+	labels := &JobLabels{
+		Name: name.String,
+		Tags: tags,
+	}
+	if err := json.Unmarshal(metadata, &labels.Metadata); err != nil {
+		log.Sugar.Errorw("error decoding job metadata",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return nil, err
+	}
+	return labels, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+// JobMetering holds the times a job's bill is metered from. Times the job hasn't reached are zero.
+// EndedAt is when the job completed, was canceled or failed
+type JobMetering struct {
+	Rate             float64
+	CreatedAt        time.Time
+	AuctionCompleted time.Time
+	DataDownloaded   time.Time
+	ImageDownloaded  time.Time
+	OutputDataPosted time.Time
+	EndedAt          time.Time
+	LastHeartbeat    time.Time
+}
+
+// GetJobMetering returns job jUUID's metering times
+func GetJobMetering(jUUID uuid.UUID) (*JobMetering, error) {
+	rate := sql.NullFloat64{}
+	var auctionCompleted, dataDownloaded, imageDownloaded, outputDataPosted, endedAt, lastHeartbeat pq.NullTime
+	m := &JobMetering{}
+	sqlStmt := `
+	SELECT j.rate, j.created_at, s.auction_completed, s.data_downloaded, s.image_downloaded,
+		s.output_data_posted, COALESCE(j.completed_at, j.canceled_at, j.failed_at), j.last_heartbeat_at
+	FROM jobs j
+	INNER JOIN statuses s ON (s.job_uuid = j.uuid)
+	WHERE j.uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&rate, &m.CreatedAt, &auctionCompleted, &dataDownloaded,
+		&imageDownloaded, &outputDataPosted, &endedAt, &lastHeartbeat); err != nil {
+		message := "error querying for job metering"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return nil, err
+	}
+
+	m.Rate = rate.Float64
+	m.AuctionCompleted = auctionCompleted.Time
+	m.DataDownloaded = dataDownloaded.Time
+	m.ImageDownloaded = imageDownloaded.Time
+	m.OutputDataPosted = outputDataPosted.Time
+	m.EndedAt = endedAt.Time
+	m.LastHeartbeat = lastHeartbeat.Time
+	return m, nil
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetJobMetrics returns rows holding the metric points for job jUUID with step >= sinceStep,
+// optionally restricted to metric name (empty matches all)
+func GetJobMetrics(jUUID uuid.UUID, name string, sinceStep int64) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT step, name, value, recorded_at
+	FROM metrics
+	WHERE job_uuid = $1 AND
+		($2 = '' OR name = $2) AND
+		step >= $3
+	ORDER BY name, step, recorded_at
+	`
+	rows, err := db.Query(sqlStmt, jUUID, name, sinceStep)
+	if err != nil {
+		message := "error querying for job metrics"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+	}
+	return rows, err
+}
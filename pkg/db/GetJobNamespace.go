@@ -0,0 +1,46 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetJobNamespace returns the uuid under which job jUUID's project data and images are
+// stored--its organization for org projects, otherwise its owner--and the project name
+func GetJobNamespace(r *http.Request, jUUID uuid.UUID) (uuid.UUID, string, error) {
+	nsUUID := uuid.UUID{}
+	var project string
+	sqlStmt := `
+	SELECT COALESCE(p.org_uuid, p.user_uuid), p.name
+	FROM projects p
+	INNER JOIN jobs j ON (j.project_uuid = p.uuid)
+	WHERE j.uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&nsUUID, &project); err != nil {
+		message := "error querying for job namespace"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return uuid.UUID{}, "", err
+	}
+	return nsUUID, project, nil
+}
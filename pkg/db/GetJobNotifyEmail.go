@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetJobNotifyEmail returns the email of job jUUID's owner, whether they've opted into
+// job notification emails, and the job's project
+func GetJobNotifyEmail(jUUID uuid.UUID) (string, bool, string, error) {
+	var email sql.NullString
+	var notify bool
+	var project string
+	sqlStmt := `
+	SELECT a.email, a.notify_email, proj.name
+	FROM jobs j
+	INNER JOIN accounts a ON (a.uuid = j.creator_uuid)
+	INNER JOIN projects proj ON (proj.uuid = j.project_uuid)
+	WHERE j.uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&email, &notify, &project); err != nil {
+		message := "error querying for job notify email"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return "", false, "", err
+	}
+
+	return email.String, notify, project, nil
+}
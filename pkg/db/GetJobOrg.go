@@ -0,0 +1,45 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetJobOrg returns the uuid of the organization owning job jUUID's project, or uuid.Nil
+// if it's a personal project
+func GetJobOrg(r *http.Request, jUUID uuid.UUID) (uuid.UUID, error) {
+	oUUID := uuid.NullUUID{}
+	sqlStmt := `
+	SELECT p.org_uuid
+	FROM projects p
+	INNER JOIN jobs j ON (j.project_uuid = p.uuid)
+	WHERE j.uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&oUUID); err != nil {
+		message := "error querying for job org"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return uuid.Nil, err
+	}
+	return oUUID.UUID, nil
+}
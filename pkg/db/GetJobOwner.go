@@ -7,14 +7,13 @@ import (
 	"net/http"
 )
 
-// GetJobOwner returns user uuid of job jUUID owner
+// GetJobOwner returns user uuid of job jUUID owner, the user who created it
 func GetJobOwner(r *http.Request, jUUID uuid.UUID) (uuid.UUID, error) {
 	uUUID := uuid.UUID{}
 	sqlStmt := `
-	SELECT p.user_uuid
-	FROM projects p
-	INNER JOIN jobs j ON (j.project_uuid = p.uuid)
-	WHERE j.uuid = $1
+	SELECT creator_uuid
+	FROM jobs
+	WHERE uuid = $1
 	`
 	if err := db.QueryRow(sqlStmt, jUUID).Scan(&uUUID); err != nil {
 		message := "error querying for job owner"
@@ -7,12 +7,12 @@ import (
 	"net/http"
 )
 
-// GetJobOwnerAndProject returns user uuid and project of job jUUID
+// GetJobOwnerAndProject returns the uuid of the user who created job jUUID and its project
 func GetJobOwnerAndProject(r *http.Request, jUUID uuid.UUID) (uuid.UUID, string, error) {
 	uUUID := uuid.UUID{}
 	var project string
 	sqlStmt := `
-	SELECT p.user_uuid, p.name
+	SELECT j.creator_uuid, p.name
 	FROM projects p
 	INNER JOIN jobs j ON (j.project_uuid = p.uuid)
 	WHERE j.uuid = $1
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// JobRefundInfo is what can still be refunded to a job's payer or reversed from its miner.
+// Amounts are in cents
+type JobRefundInfo struct {
+	UserCharged     bool
+	Charged         int64
+	Refunded        int64
+	MinerPaid       bool
+	MinerTransferID string
+	Transferred     int64
+	Reversed        int64
+}
+
+// jobRefundInfoSQL selects a JobRefundInfo for job $1. Pending adjustments count as made
+const jobRefundInfoSQL = `
+	SELECT p.user_charged_at IS NOT NULL,
+		COALESCE(p.user_charged_amt, 0) + COALESCE(p.user_charged_credit, 0),
+		COALESCE((SELECT SUM(amount) FROM payment_adjustments
+			WHERE job_uuid = p.job_uuid AND kind = 'refund'), 0)::bigint,
+		p.miner_paid_at IS NOT NULL,
+		COALESCE(p.miner_paid_id, ''),
+		COALESCE(p.miner_paid_amt, 0),
+		COALESCE((SELECT SUM(amount) FROM payment_adjustments
+			WHERE job_uuid = p.job_uuid AND kind = 'transfer_reversal'), 0)::bigint
+	FROM payments p
+	WHERE p.job_uuid = $1
+	`
+
+// GetJobRefundInfo returns how much job jUUID's payer was charged and its miner paid, and how
+// much of each has already been refunded or reversed
+func GetJobRefundInfo(jUUID uuid.UUID) (*JobRefundInfo, error) {
+	ri := &JobRefundInfo{}
+	if err := db.QueryRow(jobRefundInfoSQL, jUUID).Scan(&ri.UserCharged, &ri.Charged, &ri.Refunded,
+		&ri.MinerPaid, &ri.MinerTransferID, &ri.Transferred, &ri.Reversed); err != nil {
+		message := "error querying for job refund info"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return nil, err
+	}
+	return ri, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrys/pkg/job"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetJobSpecs returns the specs job jUUID was auctioned with. Returns sql.ErrNoRows if it
+// hasn't been auctioned
+func GetJobSpecs(r *http.Request, jUUID uuid.UUID) (*job.Specs, error) {
+	specs := &job.Specs{}
+	sqlStmt := `
+	SELECT rate, gpu, ram, disk, pcie
+	FROM requirements
+	WHERE job_uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&specs.Rate, &specs.GPU, &specs.RAM, &specs.Disk,
+		&specs.Pcie); err == sql.ErrNoRows {
+		return nil, err
+	} else if err != nil {
+		message := "error querying for job requirements"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return nil, err
+	}
+	return specs, nil
+}
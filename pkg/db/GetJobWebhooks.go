@@ -0,0 +1,40 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetJobWebhooks returns rows holding the url and secret of each webhook registered by job jUUID's owner
+func GetJobWebhooks(jUUID uuid.UUID) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT wh.url, wh.secret
+	FROM webhooks wh
+	INNER JOIN jobs j ON (j.creator_uuid = wh.account_uuid)
+	WHERE j.uuid = $1 AND
+		wh.deleted_at IS NULL
+	`
+	rows, err := db.Query(sqlStmt, jUUID)
+	if err != nil {
+		message := "error querying for job webhooks"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetJobsWithLimits returns rows holding the uuid, owner, project, notebook, rate, created_at, seconds
+// billed so far, max_runtime (seconds, 0 if none), max_spend (cents, 0 if none) and whether the
+// owner has been warned for every auctioned job with a runtime or spend limit that hasn't yet
+// been reached
+func GetJobsWithLimits() (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT j.uuid,
+		j.creator_uuid,
+		proj.name,
+		j.notebook,
+		j.rate,
+		j.created_at,
+		` + runningSecondsSQL + `,
+		COALESCE(j.max_runtime, 0),
+		COALESCE(j.max_spend, 0),
+		j.limit_warned_at IS NOT NULL
+	FROM jobs j
+	INNER JOIN projects proj ON (proj.uuid = j.project_uuid)
+	INNER JOIN statuses s ON (s.job_uuid = j.uuid)
+	WHERE j.state IN ('auctioned', 'running', 'uploading') AND
+		j.rate IS NOT NULL AND
+		j.limit_reached_at IS NULL AND
+		(j.max_runtime IS NOT NULL OR j.max_spend IS NOT NULL)
+	`
+	rows, err := db.Query(sqlStmt)
+	if err != nil {
+		message := "error querying for jobs with limits"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+			)
+		}
+	}
+	return rows, err
+}
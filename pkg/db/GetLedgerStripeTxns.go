@@ -0,0 +1,42 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+// GetLedgerStripeTxns returns rows holding the txn uuid, kind, job (if any), stripe id and
+// stripe-facing amount (the user receivable or stripe balance side, cents) of ledger transactions
+// made through stripe since since
+func GetLedgerStripeTxns(since time.Time) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT txn_uuid, kind, job_uuid, stripe_id,
+		COALESCE(SUM(ABS(amount)) FILTER (WHERE account IN ('user_receivable', 'stripe_balance')), 0)
+	FROM ledger_entries
+	WHERE stripe_id IS NOT NULL AND
+		created_at >= $1
+	GROUP BY txn_uuid, kind, job_uuid, stripe_id
+	ORDER BY MIN(created_at)
+	`
+	rows, err := db.Query(sqlStmt, since)
+	if err != nil {
+		message := "error querying for ledger stripe txns"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetMinerCollateral returns rows holding the job uuid, amount, remaining, held_at, release_at,
+// released_amt and released_at of collateral held back from miner mUUID's payouts, newest first.
+// Released collateral is only included if includeReleased
+func GetMinerCollateral(r *http.Request, mUUID uuid.UUID, includeReleased bool) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT job_uuid, amount, remaining, held_at, release_at, released_amt, released_at
+	FROM miner_collateral
+	WHERE miner_uuid = $1 AND
+		($2 OR released_at IS NULL)
+	ORDER BY held_at DESC
+	`
+	rows, err := db.Query(sqlStmt, mUUID, includeReleased)
+	if err != nil {
+		message := "error querying for miner collateral"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"mID", mUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"mID", mUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetMinerCollateralHeld returns the collateral (cents) currently held back from miner mUUID's payouts
+func GetMinerCollateralHeld(mUUID uuid.UUID) (int64, error) {
+	var held int64
+	sqlStmt := `
+	SELECT COALESCE(SUM(remaining), 0)::bigint
+	FROM miner_collateral
+	WHERE miner_uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, mUUID).Scan(&held); err != nil {
+		message := "error querying for miner collateral held"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"mID", mUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"mID", mUUID,
+			)
+		}
+		return 0, err
+	}
+	return held, nil
+}
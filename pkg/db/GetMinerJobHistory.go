@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetMinerJobHistory returns rows holding the JobHistoryRow columns followed by the recorded
+// miner_amount and miner_collateral_held (null until the miner is paid) and the percent and fixed
+// of the fee schedule that applies (null if none) of jobs won by miner mUUID, filtered and paged
+// by f. f.Project is ignored; miners don't see users' project names
+func GetMinerJobHistory(mUUID uuid.UUID, f *JobHistoryFilter) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT j.uuid, j.state, j.notebook, j.created_at,
+		j.completed_at, j.canceled_at, j.failed_at, j.rate, b.gpu,
+		s.auction_completed, s.data_downloaded, s.image_downloaded, s.output_data_posted,
+		j.last_heartbeat_at, jf.miner_amount, pay.miner_collateral_held, fs.percent, fs.fixed
+	FROM jobs j
+	INNER JOIN bids b ON (b.uuid = j.win_bid_uuid)
+	INNER JOIN statuses s ON (s.job_uuid = j.uuid)
+	INNER JOIN projects proj ON (proj.uuid = j.project_uuid)
+	INNER JOIN accounts a ON (a.uuid = j.creator_uuid)
+	LEFT JOIN orgs o ON (o.uuid = proj.org_uuid)
+	LEFT JOIN job_fees jf ON (jf.job_uuid = j.uuid)
+	LEFT JOIN payments pay ON (pay.job_uuid = j.uuid)
+	LEFT JOIN LATERAL (
+		SELECT percent, fixed
+		FROM fee_schedules
+		WHERE (gpu = b.gpu OR gpu IS NULL) AND
+			(tier = COALESCE(o.fee_tier, a.fee_tier) OR tier IS NULL) AND
+			effective_at <= j.created_at
+		ORDER BY tier IS NULL, gpu IS NULL, effective_at DESC
+		LIMIT 1
+	) fs ON TRUE
+	WHERE b.miner_uuid = $1 AND
+		($2 = '' OR j.state = $2) AND
+		($3::timestamptz IS NULL OR j.created_at >= $3) AND
+		($4::timestamptz IS NULL OR j.created_at < $4) AND
+		($5::timestamptz IS NULL OR (j.created_at, j.uuid) < ($5, $6))
+	ORDER BY j.created_at DESC, j.uuid DESC
+	LIMIT $7
+	`
+	rows, err := db.Query(sqlStmt, mUUID, f.State, nullTime(f.Since), nullTime(f.Until),
+		nullTime(f.AfterCreatedAt), f.AfterUUID, f.Limit)
+	if err != nil {
+		message := "error querying for miner job history"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"mID", mUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"mID", mUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetOrgBilling returns organization oUUID's billing email, stripe customer id and
+// stripe subscription id. The stripe ids are empty until payment info is added
+func GetOrgBilling(r *http.Request, oUUID uuid.UUID) (string, string, string, error) {
+	var billingEmail string
+	var stripeCustomerID, stripeSubscriptionID sql.NullString
+	sqlStmt := `
+	SELECT billing_email, stripe_customer_id, stripe_subscription_id
+	FROM orgs
+	WHERE uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, oUUID).Scan(&billingEmail, &stripeCustomerID, &stripeSubscriptionID); err != nil {
+		message := "error querying for org billing"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+			)
+		}
+		return "", "", "", err
+	}
+	return billingEmail, stripeCustomerID.String, stripeSubscriptionID.String, nil
+}
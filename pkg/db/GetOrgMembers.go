@@ -0,0 +1,41 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetOrgMembers returns rows holding the account uuid, email, role and join time of
+// organization oUUID's members
+func GetOrgMembers(oUUID uuid.UUID) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT a.uuid, a.email, m.role, m.created_at
+	FROM org_members m
+	INNER JOIN accounts a ON (a.uuid = m.account_uuid)
+	WHERE m.org_uuid = $1
+	ORDER BY m.created_at
+	`
+	rows, err := db.Query(sqlStmt, oUUID)
+	if err != nil {
+		message := "error querying for org members"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"oID", oUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"oID", oUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetOrgProjects returns rows holding the name, job count, active job count and
+// last job created_at of organization oUUID's projects
+func GetOrgProjects(oUUID uuid.UUID) (*sql.Rows, error) {
+	sqlStmt := `
+	SELECT proj.name, COUNT(j.uuid),
+		COUNT(j.uuid) FILTER (WHERE j.state NOT IN ('finished', 'canceled', 'failed')),
+		MAX(j.created_at)
+	FROM projects proj
+	LEFT JOIN jobs j ON (j.project_uuid = proj.uuid)
+	WHERE proj.org_uuid = $1 AND
+		proj.deleted_at IS NULL
+	GROUP BY proj.uuid, proj.name
+	ORDER BY proj.name
+	`
+	rows, err := db.Query(sqlStmt, oUUID)
+	if err != nil {
+		message := "error querying for org projects"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"oID", oUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"oID", oUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetOrgRole returns account aUUID's role in organization oUUID, or the empty role if
+// it isn't a member
+func GetOrgRole(r *http.Request, oUUID, aUUID uuid.UUID) (OrgRole, error) {
+	var role OrgRole
+	sqlStmt := `
+	SELECT role
+	FROM org_members
+	WHERE (org_uuid, account_uuid) = ($1, $2)
+	`
+	if err := db.QueryRow(sqlStmt, oUUID, aUUID).Scan(&role); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		message := "error querying for org role"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"aID", aUUID,
+			)
+		}
+		return "", err
+	}
+	return role, nil
+}
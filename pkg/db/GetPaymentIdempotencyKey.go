@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetPaymentIdempotencyKey returns the stripe idempotency key for payment operation kind on job
+// jUUID, persisting it first if this is the operation's first attempt. Keys are derived from the
+// job and operation, so every attempt sends stripe the same key
+func GetPaymentIdempotencyKey(kind PaymentTaskKind, jUUID uuid.UUID) (string, error) {
+	var key string
+	sqlStmt := `
+	INSERT INTO payment_idempotency_keys (job_uuid, kind, key)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (job_uuid, kind) DO UPDATE
+	SET key = payment_idempotency_keys.key
+	RETURNING key
+	`
+	if err := db.QueryRow(sqlStmt, jUUID, kind, fmt.Sprintf("%s-%s", kind, jUUID)).Scan(&key); err != nil {
+		message := "error getting payment idempotency key"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"kind", kind,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"kind", kind,
+			)
+		}
+		return "", err
+	}
+	return key, nil
+}
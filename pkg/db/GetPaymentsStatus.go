@@ -0,0 +1,39 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// GetPaymentsStatus returns whether job jUUID's user has been charged, its miner paid and its
+// miner charged
+func GetPaymentsStatus(jUUID uuid.UUID) (bool, bool, bool, error) {
+	var userCharged, minerPaid, minerCharged bool
+	sqlStmt := `
+	SELECT user_charged_at IS NOT NULL, miner_paid_at IS NOT NULL, miner_charged_at IS NOT NULL
+	FROM payments
+	WHERE job_uuid = $1
+	`
+	if err := db.QueryRow(sqlStmt, jUUID).Scan(&userCharged, &minerPaid, &minerCharged); err != nil {
+		message := "error querying for payments status"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return false, false, false, err
+	}
+	return userCharged, minerPaid, minerCharged, nil
+}
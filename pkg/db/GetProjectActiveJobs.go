@@ -0,0 +1,53 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetProjectActiveJobs returns the number of user uUUID's jobs in project which haven't
+// ended. Returns sql.ErrNoRows if the user has no such project
+func GetProjectActiveJobs(r *http.Request, uUUID uuid.UUID, project string) (int64, error) {
+	var n int64
+	sqlStmt := `
+	SELECT COUNT(j.uuid) FILTER (WHERE j.state NOT IN ('finished', 'canceled', 'failed'))
+	FROM projects proj
+	LEFT JOIN jobs j ON (j.project_uuid = proj.uuid)
+	WHERE (proj.name, proj.user_uuid) = ($1, $2) AND
+		proj.deleted_at IS NULL AND
+		proj.org_uuid IS NULL
+	GROUP BY proj.uuid
+	`
+	if err := db.QueryRow(sqlStmt, project, uUUID).Scan(&n); err == sql.ErrNoRows {
+		return 0, err
+	} else if err != nil {
+		message := "error querying for project active jobs"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+			)
+		}
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// GetProjectBusy returns the op user uUUID's project is undergoing, or "" if none. Returns
+// sql.ErrNoRows if the user has no such project
+func GetProjectBusy(r *http.Request, uUUID uuid.UUID, project string) (ProjectOp, error) {
+	busy := sql.NullString{}
+	sqlStmt := `
+	SELECT busy
+	FROM projects
+	WHERE (name, user_uuid) = ($1, $2) AND
+		deleted_at IS NULL AND
+		org_uuid IS NULL
+	`
+	if err := db.QueryRow(sqlStmt, project, uUUID).Scan(&busy); err == sql.ErrNoRows {
+		return "", err
+	} else if err != nil {
+		message := "error querying for project busy"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+			)
+		}
+		return "", err
+	}
+	return ProjectOp(busy.String), nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// Quota holds an account's or organization's usage limits and its usage against them.
+// Limits of 0 are unlimited
+type Quota struct {
+	PeriodStart        time.Time
+	MaxConcurrentJobs  int64
+	MaxMonthlySpend    int64 // cents
+	MaxMonthlyGPUHours float64
+	ConcurrentJobs     int64
+	MonthlySpend       int64 // cents
+	MonthlyGPUHours    float64
+}
+
+// GetQuota returns the quota of organization oUUID or, if it's uuid.Nil, of account aUUID's
+// personal projects. Concurrent jobs are those auctioned but not yet ended, or reserved for
+// auction by ReserveJobAuction; spend and
+// GPU-hours cover jobs created since the start of the current UTC month
+func GetQuota(r *http.Request, aUUID, oUUID uuid.UUID) (*Quota, error) {
+	q := newQuota()
+	if message, err := queryQuota(db, q, aUUID, oUUID, uuid.Nil); err != nil {
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"oID", oUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"oID", oUUID,
+			)
+		}
+		return nil, err
+	}
+	return q, nil
+}
+
+// auctionReserveWindow is how long a job reserved by ReserveJobAuction counts towards
+// concurrent jobs before it's auctioned, comfortably longer than an auction runs
+const auctionReserveWindow = "1 minute"
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func newQuota() *Quota {
+	t := time.Now().UTC()
+	return &Quota{
+		PeriodStart: time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// queryQuota fills q's limits and usage. Jobs reserved for auction within auctionReserveWindow,
+// other than jUUID, count as concurrent
+func queryQuota(qr queryRower, q *Quota, aUUID, oUUID, jUUID uuid.UUID) (string, error) {
+	org := uuid.NullUUID{UUID: oUUID, Valid: !uuid.Equal(oUUID, uuid.Nil)}
+	sqlStmt := `
+	SELECT COALESCE(max_concurrent_jobs, 0), COALESCE(max_monthly_spend, 0), COALESCE(max_monthly_gpu_hours, 0)
+	FROM accounts
+	WHERE uuid = $1
+	`
+	if org.Valid {
+		sqlStmt = `
+	SELECT COALESCE(max_concurrent_jobs, 0), COALESCE(max_monthly_spend, 0), COALESCE(max_monthly_gpu_hours, 0)
+	FROM orgs
+	WHERE uuid = $1
+	`
+		if err := qr.QueryRow(sqlStmt, oUUID).Scan(&q.MaxConcurrentJobs, &q.MaxMonthlySpend, &q.MaxMonthlyGPUHours); err != nil {
+			return "error querying for org limits", err
+		}
+	} else if err := qr.QueryRow(sqlStmt, aUUID).Scan(&q.MaxConcurrentJobs, &q.MaxMonthlySpend, &q.MaxMonthlyGPUHours); err != nil {
+		return "error querying for account limits", err
+	}
+
+	sqlStmt = `
+	SELECT COUNT(*) FILTER (WHERE u.state IN ('auctioned', 'running', 'uploading') OR
+			(u.state IN ('created', 'data_synced', 'image_built') AND u.uuid <> $4 AND
+			u.auction_reserved_at > NOW() - INTERVAL '` + auctionReserveWindow + `')),
+		COALESCE(SUM(CASE WHEN u.seconds > 0 THEN GREATEST(ROUND(u.rate * CEIL(u.seconds) / 36), 1) ELSE 0 END)
+			FILTER (WHERE u.rate IS NOT NULL AND u.created_at >= $3), 0)::bigint,
+		COALESCE(SUM(u.seconds / 3600) FILTER (WHERE u.rate IS NOT NULL AND u.created_at >= $3), 0)
+	FROM (
+		SELECT j.uuid, j.state, j.rate, j.created_at, j.auction_reserved_at, ` + runningSecondsSQL + ` AS seconds
+		FROM jobs j
+		INNER JOIN projects p ON (p.uuid = j.project_uuid)
+		INNER JOIN statuses s ON (s.job_uuid = j.uuid)
+		WHERE (j.rate IS NOT NULL OR j.auction_reserved_at > NOW() - INTERVAL '` + auctionReserveWindow + `') AND
+			(p.org_uuid = $2 OR ($2 IS NULL AND p.org_uuid IS NULL AND p.user_uuid = $1)) AND
+			(j.created_at >= $3 OR j.state IN ('created', 'data_synced', 'image_built', 'auctioned', 'running', 'uploading'))
+	) u
+	`
+	if err := qr.QueryRow(sqlStmt, aUUID, org, q.PeriodStart, jUUID).Scan(&q.ConcurrentJobs, &q.MonthlySpend, &q.MonthlyGPUHours); err != nil {
+		return "error querying for usage", err
+	}
+
+	return "", nil
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+// GetUsageReport returns rows holding the project, creator email, gpu, job count, GPU-seconds,
+// credit applied (cents) and amount invoiced (cents) of the jobs of organization oUUID or, if
+// it's uuid.Nil, account aUUID's personal projects which ended in [start, end)
+func GetUsageReport(aUUID, oUUID uuid.UUID, start, end time.Time) (*sql.Rows, error) {
+	org := uuid.NullUUID{UUID: oUUID, Valid: !uuid.Equal(oUUID, uuid.Nil)}
+	sqlStmt := `
+	SELECT proj.name, a.email, COALESCE(b.gpu, ''), COUNT(*),
+		COALESCE(SUM(` + runningSecondsSQL + `), 0),
+		COALESCE(SUM(pay.user_charged_credit), 0)::bigint,
+		COALESCE(SUM(pay.user_charged_amt), 0)::bigint
+	FROM jobs j
+	INNER JOIN projects proj ON (proj.uuid = j.project_uuid)
+	INNER JOIN accounts a ON (a.uuid = j.creator_uuid)
+	INNER JOIN payments pay ON (pay.job_uuid = j.uuid)
+	INNER JOIN statuses s ON (s.job_uuid = j.uuid)
+	LEFT JOIN bids b ON (b.uuid = j.win_bid_uuid)
+	WHERE j.rate IS NOT NULL AND
+		(proj.org_uuid = $2 OR ($2 IS NULL AND proj.org_uuid IS NULL AND proj.user_uuid = $1)) AND
+		COALESCE(j.completed_at, j.canceled_at, j.failed_at) >= $3 AND
+		COALESCE(j.completed_at, j.canceled_at, j.failed_at) < $4
+	GROUP BY proj.name, a.email, b.gpu
+	ORDER BY proj.name, a.email, b.gpu
+	`
+	rows, err := db.Query(sqlStmt, aUUID, org, start, end)
+	if err != nil {
+		message := "error querying for usage report"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"aID", aUUID,
+				"oID", oUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"aID", aUUID,
+				"oID", oUUID,
+			)
+		}
+	}
+	return rows, err
+}
@@ -19,7 +19,7 @@ func GetValidBids(r *http.Request, jUUID uuid.UUID) (*sql.Rows, error) {
 		NOT EXISTS(SELECT 1
 			FROM bids b2
 			INNER JOIN jobs j ON (b2.uuid = j.win_bid_uuid)
-			WHERE j.active = true 
+			WHERE j.state NOT IN ('finished', 'canceled', 'failed')
 				AND b2.device_uuid = b1.device_uuid
 				AND b2.miner_uuid = b2.miner_uuid
 		)
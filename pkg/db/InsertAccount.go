@@ -16,10 +16,11 @@ var (
 )
 
 const (
-	errEmailExistsCode   = "23505"
-	errNullViolationCode = "23502"
-	errBeginTx           = "error beginning tx"
-	errCommitTx          = "error committing tx"
+	errEmailExistsCode     = "23505"
+	errNullViolationCode   = "23502"
+	errUniqueViolationCode = "23505"
+	errBeginTx             = "error beginning tx"
+	errCommitTx            = "error committing tx"
 )
 
 // InsertAccount inserts a new account into the db
@@ -39,6 +40,19 @@ func InsertAccount(r *http.Request, email, hashedPassword string, aUUID uuid.UUI
 			return "error inserting account", err
 		}
 
+		if newUserCredit > 0 {
+			lt := &LedgerTxn{
+				Kind: LedgerKindCreditGrant,
+				Entries: []LedgerEntry{
+					{Account: LedgerPromoExpense, Amount: int64(newUserCredit)},
+					{Account: LedgerUserCredit, Owner: aUUID, Amount: -int64(newUserCredit)},
+				},
+			}
+			if err := insertLedgerTxn(tx, lt); err != nil {
+				return "error inserting credit grant ledger txn", err
+			}
+		}
+
 		if isUser {
 			sqlStmt = `
 			INSERT INTO users (uuid)
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// InsertCheckpoint inserts (or replaces) checkpoint name of size bytes for job jUUID
+// and updates the job's stored output size accordingly
+func InsertCheckpoint(r *http.Request, jUUID uuid.UUID, name string, size int64) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		var oldSize int64
+		sqlStmt := `
+		SELECT size
+		FROM checkpoints
+		WHERE job_uuid = $1 AND
+			name = $2
+		FOR UPDATE
+		`
+		if err := tx.QueryRow(sqlStmt, jUUID, name).Scan(&oldSize); err != nil && err != sql.ErrNoRows {
+			return "error querying checkpoints size", err
+		}
+
+		sqlStmt = `
+		INSERT INTO checkpoints (job_uuid, name, size)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (job_uuid, name) DO UPDATE
+		SET size = EXCLUDED.size,
+			posted_at = NOW()
+		`
+		if _, err := tx.Exec(sqlStmt, jUUID, name, size); err != nil {
+			return "error inserting checkpoint", err
+		}
+
+		sqlStmt = `
+		UPDATE jobs
+		SET output_bytes = output_bytes + $2
+		WHERE uuid = $1
+		`
+		if _, err := tx.Exec(sqlStmt, jUUID, size-oldSize); err != nil {
+			return "error updating jobs output_bytes", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		return err
+	}
+	return nil
+}
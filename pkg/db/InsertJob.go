@@ -8,41 +8,58 @@ import (
 	"net/http"
 )
 
-// InsertJob inserts a new job, status, and payment into the db
-func InsertJob(r *http.Request, uUUID uuid.UUID, project string, jUUID uuid.UUID, notebook bool) error {
+// InsertJob inserts a new job, status, and payment into the db. The project belongs to
+// organization oUUID unless it's uuid.Nil. maxRuntime (seconds) and maxSpend (cents) limit
+// the job when positive. Returns ErrProjectBusy if the project is being deleted or renamed
+func InsertJob(r *http.Request, uUUID, oUUID uuid.UUID, project string, jUUID uuid.UUID, notebook bool,
+	maxRuntime, maxSpend int64, labels *JobLabels) error {
 	ctx := r.Context()
 	tx, txerr := db.BeginTx(ctx, nil)
 	if message, err := func() (string, error) {
 		if txerr != nil {
 			return errBeginTx, txerr
 		}
+		org := uuid.NullUUID{UUID: oUUID, Valid: !uuid.Equal(oUUID, uuid.Nil)}
 		pUUID := uuid.UUID{}
+		busy := sql.NullString{}
 		sqlStmt := `
-	SELECT uuid
+	SELECT uuid, busy
 	FROM projects
-	WHERE (name, user_uuid) = ($1, $2)
+	WHERE name = $1 AND
+		(org_uuid = $3 OR ($3 IS NULL AND org_uuid IS NULL AND user_uuid = $2)) AND
+		deleted_at IS NULL
+	FOR SHARE
 	`
-		if err := tx.QueryRow(sqlStmt, project, uUUID).Scan(&pUUID); err != nil {
+		if err := tx.QueryRow(sqlStmt, project, uUUID, org).Scan(&pUUID, &busy); err != nil {
 			if err == sql.ErrNoRows {
 				pUUID = uuid.NewV4()
 				sqlStmt = `
-	INSERT INTO projects (uuid, name, user_uuid)
-	VALUES ($1, $2, $3)
+	INSERT INTO projects (uuid, name, user_uuid, org_uuid)
+	VALUES ($1, $2, $3, $4)
 	`
-				if _, err := tx.Exec(sqlStmt, pUUID, project, uUUID); err != nil {
+				if _, err := tx.Exec(sqlStmt, pUUID, project, uUUID, org); err != nil {
 					return "error inserting project", err
 				}
 
 			} else {
 				return "error finding project", err
 			}
+		} else if busy.Valid {
+			return "project is busy", ErrProjectBusy
 		}
 
+		metadata, err := metadataJSON(labels.Metadata)
+		if err != nil {
+			return "error encoding job metadata", err
+		}
 		sqlStmt = `
-	INSERT INTO jobs (uuid, project_uuid, active, notebook)
-	VALUES ($1, $2, true, $3)
+	INSERT INTO jobs (uuid, project_uuid, creator_uuid, state, notebook, max_runtime, max_spend, name, tags, metadata)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), COALESCE($9, '{}'), $10)
 	`
-		if _, err := tx.Exec(sqlStmt, jUUID, pUUID, notebook); err != nil {
+		if _, err := tx.Exec(sqlStmt, jUUID, pUUID, uUUID, JobStateCreated, notebook,
+			sql.NullInt64{Int64: maxRuntime, Valid: maxRuntime > 0},
+			sql.NullInt64{Int64: maxSpend, Valid: maxSpend > 0},
+			labels.Name, pq.Array(labels.Tags), string(metadata)); err != nil {
 			return "error inserting job", err
 		}
 
@@ -67,6 +84,12 @@ func InsertJob(r *http.Request, uUUID uuid.UUID, project string, jUUID uuid.UUID
 
 		return "", nil
 	}(); err != nil {
+		if err == ErrProjectBusy {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+			return err
+		}
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			log.Sugar.Errorw(message,
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// InsertJobArray inserts job array arrUUID, built from job jUUID, for user uUUID
+func InsertJobArray(r *http.Request, arrUUID, uUUID, jUUID uuid.UUID) error {
+	sqlStmt := `
+	INSERT INTO job_arrays (uuid, user_uuid, source_job_uuid)
+	VALUES ($1, $2, $3)
+	`
+	if _, err := db.Exec(sqlStmt, arrUUID, uUUID, jUUID); err != nil {
+		message := "error inserting job array"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"arrID", arrUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"arrID", arrUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
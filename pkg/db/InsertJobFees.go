@@ -0,0 +1,40 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// InsertJobFees records jf as job jUUID's fees unless some are recorded already, and returns the
+// recorded fees, so the user charge and miner payout always use the same split
+func InsertJobFees(jUUID uuid.UUID, jf *JobFees) (*JobFees, error) {
+	sqlStmt := `
+	INSERT INTO job_fees (job_uuid, fee_schedule_id, amount, fee, miner_amount)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (job_uuid) DO NOTHING
+	`
+	feeScheduleID := sql.NullInt64{Int64: jf.FeeScheduleID, Valid: jf.FeeScheduleID != 0}
+	if _, err := db.Exec(sqlStmt, jUUID, feeScheduleID, jf.Amount, jf.Fee, jf.MinerAmount); err != nil {
+		message := "error inserting job fees"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return nil, err
+	}
+	return GetJobFees(jUUID)
+}
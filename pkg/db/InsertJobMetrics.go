@@ -0,0 +1,78 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+// Metric is a single structured metric point reported by a job
+type Metric struct {
+	Step  int64     `json:"step"`
+	Name  string    `json:"name"`
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// InsertJobMetrics inserts a batch of metric points for job jUUID
+func InsertJobMetrics(r *http.Request, jUUID uuid.UUID, metrics []Metric) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		stmt, err := tx.Prepare(pq.CopyIn("metrics", "job_uuid", "step", "name", "value", "recorded_at"))
+		if err != nil {
+			return "error preparing metrics copy", err
+		}
+		for _, m := range metrics {
+			if _, err := stmt.Exec(jUUID, m.Step, m.Name, m.Value, m.Time); err != nil {
+				return "error copying metric", err
+			}
+		}
+		if _, err := stmt.Exec(); err != nil {
+			return "error flushing metrics copy", err
+		}
+		if err := stmt.Close(); err != nil {
+			return "error closing metrics copy", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		return err
+	}
+	return nil
+}
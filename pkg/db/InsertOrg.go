@@ -0,0 +1,78 @@
+package db
+
+import (
+	"errors"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ErrOrgExists lets server send a proper response to client
+var ErrOrgExists = errors.New("an organization with this name already exists")
+
+// InsertOrg inserts a new organization with account aUUID as its owner
+func InsertOrg(r *http.Request, oUUID uuid.UUID, name, billingEmail string, aUUID uuid.UUID) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
+	INSERT INTO orgs (uuid, name, billing_email)
+	VALUES ($1, $2, $3)
+	`
+		if _, err := tx.Exec(sqlStmt, oUUID, name, billingEmail); err != nil {
+			return "error inserting org", err
+		}
+
+		sqlStmt = `
+	INSERT INTO org_members (org_uuid, account_uuid, role)
+	VALUES ($1, $2, $3)
+	`
+		if _, err := tx.Exec(sqlStmt, oUUID, aUUID, OrgRoleOwner); err != nil {
+			return "error inserting org owner", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok && pqErr.Code == errUniqueViolationCode {
+			return ErrOrgExists
+		}
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"aID", aUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ErrOrgMemberExists lets server send a proper response to client
+var ErrOrgMemberExists = errors.New("account is already a member of this organization")
+
+// InsertOrgMember adds the user with email to organization oUUID with role and returns
+// its account uuid. Returns sql.ErrNoRows if there is no such user
+func InsertOrgMember(r *http.Request, oUUID uuid.UUID, email string, role OrgRole) (uuid.UUID, error) {
+	aUUID := uuid.UUID{}
+	sqlStmt := `
+	INSERT INTO org_members (org_uuid, account_uuid, role)
+	SELECT $1, a.uuid, $3
+	FROM accounts a
+	INNER JOIN users u ON (u.uuid = a.uuid)
+	WHERE a.email = $2
+	RETURNING account_uuid
+	`
+	if err := db.QueryRow(sqlStmt, oUUID, email, role).Scan(&aUUID); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, err
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok && pqErr.Code == errUniqueViolationCode {
+			return uuid.Nil, ErrOrgMemberExists
+		}
+		message := "error inserting org member"
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"email", email,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"email", email,
+			)
+		}
+		return uuid.Nil, err
+	}
+	return aUUID, nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// PaymentAdjustmentKind is the kind of manual correction an admin made to a user's or miner's money
+type PaymentAdjustmentKind string
+
+// Payment adjustment kinds
+const (
+	PaymentAdjustmentRefund           PaymentAdjustmentKind = "refund"
+	PaymentAdjustmentTransferReversal PaymentAdjustmentKind = "transfer_reversal"
+	PaymentAdjustmentCredit           PaymentAdjustmentKind = "credit"
+)
+
+// PaymentAdjustment is the audit record of a manual correction. Job is uuid.Nil if it isn't tied
+// to a job. Account is the user, org or miner adjusted. Method is how the money moved
+// ("invoice", "credit" or "transfer"), and StripeID the stripe object which moved it, if any
+type PaymentAdjustment struct {
+	UUID     uuid.UUID
+	Kind     PaymentAdjustmentKind
+	Job      uuid.UUID
+	Account  uuid.UUID
+	Amount   int64
+	Method   string
+	StripeID string
+	Reason   string
+	Admin    uuid.UUID
+}
+
+// InsertPaymentAdjustment records finalized adjustment adj, adds creditDelta (cents) to account
+// adj.Account's credit and records ledger transaction lt, atomically
+func InsertPaymentAdjustment(r *http.Request, adj *PaymentAdjustment, creditDelta int64, lt *LedgerTxn) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
+		INSERT INTO payment_adjustments (uuid, kind, job_uuid, account_uuid, amount, method, stripe_id, reason, admin_uuid,
+			finalized_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, NOW())
+		`
+		if _, err := tx.Exec(sqlStmt, adj.UUID, adj.Kind,
+			uuid.NullUUID{UUID: adj.Job, Valid: !uuid.Equal(adj.Job, uuid.Nil)}, adj.Account,
+			adj.Amount, adj.Method, adj.StripeID, adj.Reason, adj.Admin); err != nil {
+			return "error inserting payment adjustment", err
+		}
+
+		if creditDelta != 0 {
+			sqlStmt = `
+		UPDATE accounts
+		SET credit = credit + $2
+		WHERE uuid = $1
+		`
+			if _, err := tx.Exec(sqlStmt, adj.Account, creditDelta); err != nil {
+				return "error updating account credit", err
+			}
+		}
+
+		if err := insertLedgerTxn(tx, lt); err != nil {
+			return "error inserting ledger txn", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adj.UUID,
+				"jID", adj.Job,
+				"aID", adj.Account,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adj.UUID,
+				"jID", adj.Job,
+				"aID", adj.Account,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// InsertWebhook registers webhook whUUID for account aUUID, posting to url signed with secret
+func InsertWebhook(r *http.Request, aUUID, whUUID uuid.UUID, url, secret string) error {
+	sqlStmt := `
+	INSERT INTO webhooks (uuid, account_uuid, url, secret)
+	VALUES ($1, $2, $3, $4)
+	`
+	if _, err := db.Exec(sqlStmt, whUUID, aUUID, url, secret); err != nil {
+		message := "error inserting webhook"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"time"
+)
+
+// JobHistoryEntry is the part of a job history entry shared by users and miners
+type JobHistoryEntry struct {
+	ID          uuid.UUID  `json:"id"`
+	State       JobState   `json:"state"`
+	Notebook    bool       `json:"notebook"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	CanceledAt  *time.Time `json:"canceledAt,omitempty"`
+	FailedAt    *time.Time `json:"failedAt,omitempty"`
+	GPU         string     `json:"gpu,omitempty"`
+	Rate        float64    `json:"rate,omitempty"`
+}
+
+// JobHistoryRow scans the columns every job history query starts with: uuid, state, notebook,
+// created_at, completed_at, canceled_at, failed_at, rate, gpu, auction_completed, data_downloaded,
+// image_downloaded, output_data_posted and last_heartbeat_at
+type JobHistoryRow struct {
+	JobHistoryEntry
+	rate                                         sql.NullFloat64
+	gpu                                          sql.NullString
+	completedAt, canceledAt, failedAt            pq.NullTime
+	auctionCompleted, dataDownloaded             pq.NullTime
+	imageDownloaded, outputDataPosted, heartbeat pq.NullTime
+}
+
+// Dest returns the scan destinations of jr's columns, to be followed by those of the query's own
+func (jr *JobHistoryRow) Dest() []interface{} {
+	return []interface{}{&jr.ID, &jr.State, &jr.Notebook, &jr.CreatedAt,
+		&jr.completedAt, &jr.canceledAt, &jr.failedAt, &jr.rate, &jr.gpu,
+		&jr.auctionCompleted, &jr.dataDownloaded, &jr.imageDownloaded, &jr.outputDataPosted, &jr.heartbeat}
+}
+
+// Entry returns the entry scanned into jr, along with the job's metering or nil if it was never
+// auctioned and so has no rate
+func (jr *JobHistoryRow) Entry() (JobHistoryEntry, *JobMetering) {
+	j := jr.JobHistoryEntry
+	j.Rate = jr.rate.Float64
+	j.GPU = jr.gpu.String
+	m := &JobMetering{
+		Rate:             jr.rate.Float64,
+		CreatedAt:        j.CreatedAt,
+		AuctionCompleted: jr.auctionCompleted.Time,
+		DataDownloaded:   jr.dataDownloaded.Time,
+		ImageDownloaded:  jr.imageDownloaded.Time,
+		OutputDataPosted: jr.outputDataPosted.Time,
+		LastHeartbeat:    jr.heartbeat.Time,
+	}
+	if t := jr.completedAt.Time; jr.completedAt.Valid {
+		j.CompletedAt, m.EndedAt = &t, t
+	} else if t := jr.canceledAt.Time; jr.canceledAt.Valid {
+		j.CanceledAt, m.EndedAt = &t, t
+	} else if t := jr.failedAt.Time; jr.failedAt.Valid {
+		j.FailedAt, m.EndedAt = &t, t
+	}
+	if !jr.rate.Valid {
+		return j, nil
+	}
+	return j, m
+}
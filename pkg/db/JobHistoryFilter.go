@@ -0,0 +1,28 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"time"
+)
+
+// JobHistoryFilter restricts and pages a job history query. Zero values don't filter;
+// results are ordered newest first and resume after (AfterCreatedAt, AfterUUID). Name,
+// Tags, Metadata and Query only apply to account history
+type JobHistoryFilter struct {
+	Project        string
+	State          JobState
+	Name           string            // case-insensitive substring of the job name
+	Tags           []string          // job has every tag
+	Metadata       map[string]string // job metadata contains every pair
+	Query          string            // case-insensitive substring of the name, a tag or a metadata value
+	Since          time.Time
+	Until          time.Time
+	AfterCreatedAt time.Time
+	AfterUUID      uuid.UUID
+	Limit          int
+}
+
+func nullTime(t time.Time) pq.NullTime {
+	return pq.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,20 @@
+package db
+
+import (
+	"encoding/json"
+)
+
+// JobLabels are the user-defined name, tags and key/value metadata attached to a job
+type JobLabels struct {
+	Name     string
+	Tags     []string
+	Metadata map[string]string
+}
+
+// metadataJSON returns m encoded for a jsonb column, treating nil as empty
+func metadataJSON(m map[string]string) ([]byte, error) {
+	if m == nil {
+		m = map[string]string{}
+	}
+	return json.Marshal(m)
+}
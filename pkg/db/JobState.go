@@ -0,0 +1,147 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/satori/go.uuid"
+)
+
+// JobState is a job's position in its lifecycle
+type JobState string
+
+// Job states, in lifecycle order
+const (
+	JobStateCreated    JobState = "created"
+	JobStateDataSynced JobState = "data_synced"
+	JobStateImageBuilt JobState = "image_built"
+	JobStateAuctioned  JobState = "auctioned"
+	JobStateRunning    JobState = "running"
+	JobStateUploading  JobState = "uploading"
+	JobStateFinished   JobState = "finished"
+	JobStateCanceled   JobState = "canceled"
+	JobStateFailed     JobState = "failed"
+)
+
+// ErrInvalidJobStateTransition is returned when a job can't move from its current state to the requested one
+var ErrInvalidJobStateTransition = errors.New("invalid job state transition")
+
+var jobStateOrder = map[JobState]int{
+	JobStateCreated:    0,
+	JobStateDataSynced: 1,
+	JobStateImageBuilt: 2,
+	JobStateAuctioned:  3,
+	JobStateRunning:    4,
+	JobStateUploading:  5,
+	JobStateFinished:   6,
+	JobStateCanceled:   6,
+	JobStateFailed:     6,
+}
+
+// terminal states have no outgoing transitions
+var jobStateTransitions = map[JobState][]JobState{
+	JobStateCreated:    {JobStateDataSynced, JobStateImageBuilt, JobStateCanceled},
+	JobStateDataSynced: {JobStateImageBuilt, JobStateCanceled},
+	JobStateImageBuilt: {JobStateAuctioned, JobStateCanceled},
+	JobStateAuctioned:  {JobStateRunning, JobStateCanceled, JobStateFailed},
+	JobStateRunning:    {JobStateUploading, JobStateCanceled, JobStateFailed},
+	JobStateUploading:  {JobStateFinished, JobStateCanceled, JobStateFailed},
+}
+
+// Valid returns whether s is a known job state
+func (s JobState) Valid() bool {
+	_, ok := jobStateOrder[s]
+	return ok
+}
+
+// Terminal returns whether the job has ended
+func (s JobState) Terminal() bool {
+	return len(jobStateTransitions[s]) == 0
+}
+
+func (s JobState) canTransition(to JobState) bool {
+	for _, next := range jobStateTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionJobState locks job jUUID's row and moves it to state to, returning
+// ErrInvalidJobStateTransition if that isn't allowed from its current state
+func transitionJobState(tx *sql.Tx, jUUID uuid.UUID, to JobState) error {
+	var from JobState
+	sqlStmt := `
+	SELECT state
+	FROM jobs
+	WHERE uuid = $1
+	FOR UPDATE
+	`
+	if err := tx.QueryRow(sqlStmt, jUUID).Scan(&from); err != nil {
+		return err
+	}
+
+	if !from.canTransition(to) {
+		return ErrInvalidJobStateTransition
+	}
+
+	sqlStmt = `
+	UPDATE jobs
+	SET state = $2
+	WHERE uuid = $1
+	`
+	_, err := tx.Exec(sqlStmt, jUUID, to)
+	return err
+}
+
+// advanceJobState locks job jUUID's row and moves it forward to the state implied by its
+// statuses. Jobs that have already ended, whose statuses don't imply a later state, or which can't
+// reach that state from their current one (e.g. a miner posting its log before it finished
+// downloading), are left alone
+func advanceJobState(tx *sql.Tx, jUUID uuid.UUID) error {
+	var from JobState
+	var dataSynced, imageBuilt, auctionCompleted, dataDownloaded, imageDownloaded, outputLogPosted bool
+	sqlStmt := `
+	SELECT j.state,
+		s.data_synced IS NOT NULL,
+		s.image_built IS NOT NULL,
+		s.auction_completed IS NOT NULL,
+		s.data_downloaded IS NOT NULL,
+		s.image_downloaded IS NOT NULL,
+		s.output_log_posted IS NOT NULL
+	FROM jobs j
+	INNER JOIN statuses s ON (s.job_uuid = j.uuid)
+	WHERE j.uuid = $1
+	FOR UPDATE OF j
+	`
+	if err := tx.QueryRow(sqlStmt, jUUID).Scan(&from, &dataSynced, &imageBuilt,
+		&auctionCompleted, &dataDownloaded, &imageDownloaded, &outputLogPosted); err != nil {
+		return err
+	}
+
+	to := JobStateCreated
+	switch {
+	case outputLogPosted:
+		to = JobStateUploading
+	case dataDownloaded && imageDownloaded:
+		to = JobStateRunning
+	case auctionCompleted:
+		to = JobStateAuctioned
+	case dataSynced && imageBuilt:
+		to = JobStateImageBuilt
+	case dataSynced:
+		to = JobStateDataSynced
+	}
+
+	if from.Terminal() || jobStateOrder[to] <= jobStateOrder[from] || !from.canTransition(to) {
+		return nil
+	}
+
+	sqlStmt = `
+	UPDATE jobs
+	SET state = $2
+	WHERE uuid = $1
+	`
+	_, err := tx.Exec(sqlStmt, jUUID, to)
+	return err
+}
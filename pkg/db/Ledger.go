@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/satori/go.uuid"
+)
+
+// LedgerAccount is an account in the payments ledger
+type LedgerAccount string
+
+// Ledger accounts
+const (
+	// LedgerStripeBalance is the platform's stripe balance (asset)
+	LedgerStripeBalance LedgerAccount = "stripe_balance"
+	// LedgerUserReceivable is job charges invoiced to users and orgs but not yet collected (asset)
+	LedgerUserReceivable LedgerAccount = "user_receivable"
+	// LedgerUserCredit is users' unspent credit (liability)
+	LedgerUserCredit LedgerAccount = "user_credit"
+	// LedgerMinerPayable is owed to miners for jobs they ran (liability)
+	LedgerMinerPayable LedgerAccount = "miner_payable"
+	// LedgerPlatformFee is the platform's share of job charges (revenue)
+	LedgerPlatformFee LedgerAccount = "platform_fee"
+	// LedgerPenalty is failure penalties charged to miners (revenue)
+	LedgerPenalty LedgerAccount = "penalty"
+	// LedgerRefunds is money returned to users (contra-revenue)
+	LedgerRefunds LedgerAccount = "refunds"
+	// LedgerPromoExpense is credit given away to users (expense)
+	LedgerPromoExpense LedgerAccount = "promo_expense"
+	// LedgerMinerCollateral is miner payouts held back against failure penalties (liability)
+	LedgerMinerCollateral LedgerAccount = "miner_collateral"
+)
+
+// LedgerKind is the kind of money movement a ledger transaction records
+type LedgerKind string
+
+// Ledger transaction kinds
+const (
+	LedgerKindJobCharge         LedgerKind = "job_charge"
+	LedgerKindMinerPayout       LedgerKind = "miner_payout"
+	LedgerKindPenalty           LedgerKind = "penalty"
+	LedgerKindRefund            LedgerKind = "refund"
+	LedgerKindCreditGrant       LedgerKind = "credit_grant"
+	LedgerKindTransferReversal  LedgerKind = "transfer_reversal"
+	LedgerKindAdjustment        LedgerKind = "adjustment"
+	LedgerKindCreditPurchase    LedgerKind = "credit_purchase"
+	LedgerKindCollateralRelease LedgerKind = "collateral_release"
+)
+
+// ErrLedgerUnbalanced is returned when a ledger transaction's debits and credits don't match
+var ErrLedgerUnbalanced = errors.New("ledger transaction is unbalanced")
+
+// LedgerEntry is one side of a ledger transaction. Amount (cents) is positive for debits and
+// negative for credits. Owner is the user, org or miner the entry belongs to, if any
+type LedgerEntry struct {
+	Account LedgerAccount
+	Owner   uuid.UUID
+	Amount  int64
+}
+
+// LedgerTxn is a balanced set of ledger entries recording one money movement. StripeID is the
+// stripe object (invoice item, transfer, charge, refund) which moved the money, if any
+type LedgerTxn struct {
+	Kind     LedgerKind
+	Job      uuid.UUID
+	StripeID string
+	Entries  []LedgerEntry
+}
+
+// insertLedgerTxn appends lt to the ledger within tx. Zero entries are skipped
+func insertLedgerTxn(tx *sql.Tx, lt *LedgerTxn) error {
+	var sum int64
+	for _, e := range lt.Entries {
+		sum += e.Amount
+	}
+	if sum != 0 {
+		return ErrLedgerUnbalanced
+	}
+
+	txnUUID := uuid.NewV4()
+	sqlStmt := `
+	INSERT INTO ledger_entries (txn_uuid, kind, job_uuid, stripe_id, account, owner_uuid, amount)
+	VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+	`
+	for _, e := range lt.Entries {
+		if e.Amount == 0 {
+			continue
+		}
+		if _, err := tx.Exec(sqlStmt, txnUUID, lt.Kind,
+			uuid.NullUUID{UUID: lt.Job, Valid: !uuid.Equal(lt.Job, uuid.Nil)}, lt.StripeID, e.Account,
+			uuid.NullUUID{UUID: e.Owner, Valid: !uuid.Equal(e.Owner, uuid.Nil)}, e.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package db
+
+// OrgRole is a member's role in an organization
+type OrgRole string
+
+// Organization roles, least to most privileged
+const (
+	OrgRoleViewer OrgRole = "viewer"
+	OrgRoleMember OrgRole = "member"
+	OrgRoleAdmin  OrgRole = "admin"
+	OrgRoleOwner  OrgRole = "owner"
+)
+
+var orgRoleRank = map[OrgRole]int{
+	OrgRoleViewer: 1,
+	OrgRoleMember: 2,
+	OrgRoleAdmin:  3,
+	OrgRoleOwner:  4,
+}
+
+// Valid returns whether role is a known organization role
+func (role OrgRole) Valid() bool {
+	_, ok := orgRoleRank[role]
+	return ok
+}
+
+// AtLeast returns whether role grants everything min does. The empty role (not a member) grants nothing
+func (role OrgRole) AtLeast(min OrgRole) bool {
+	return orgRoleRank[role] > 0 && orgRoleRank[role] >= orgRoleRank[min]
+}
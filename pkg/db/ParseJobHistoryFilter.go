@@ -0,0 +1,76 @@
+package db
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/satori/go.uuid"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultJobHistoryLimit = 50
+	maxJobHistoryLimit     = 200
+)
+
+// ParseJobHistoryFilter parses the state, since, until (RFC3339), cursor and limit job history
+// filters shared by users and miners from query q. Errors are suitable for a 400 response
+func ParseJobHistoryFilter(q url.Values) (*JobHistoryFilter, error) {
+	f := &JobHistoryFilter{
+		State: JobState(q.Get("state")),
+		Limit: defaultJobHistoryLimit,
+	}
+	if f.State != "" && !f.State.Valid() {
+		return nil, fmt.Errorf("invalid state: %s", f.State)
+	}
+	var err error
+	if since := q.Get("since"); since != "" {
+		if f.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, fmt.Errorf("since must be an RFC3339 timestamp")
+		}
+	}
+	if until := q.Get("until"); until != "" {
+		if f.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return nil, fmt.Errorf("until must be an RFC3339 timestamp")
+		}
+	}
+	if cursor := q.Get("cursor"); cursor != "" {
+		if f.AfterCreatedAt, f.AfterUUID, err = decodeJobHistoryCursor(cursor); err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+	if limit := q.Get("limit"); limit != "" {
+		if f.Limit, err = strconv.Atoi(limit); err != nil || f.Limit < 1 || f.Limit > maxJobHistoryLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxJobHistoryLimit)
+		}
+	}
+	return f, nil
+}
+
+// EncodeJobHistoryCursor returns the cursor resuming job history after the job jUUID created at createdAt
+func EncodeJobHistoryCursor(createdAt time.Time, jUUID uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(fmt.Sprintf("%s_%s", createdAt.UTC().Format(time.RFC3339Nano), jUUID)))
+}
+
+func decodeJobHistoryCursor(cursor string) (time.Time, uuid.UUID, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	parts := strings.SplitN(string(b), "_", 2)
+	if len(parts) != 2 {
+		return time.Time{}, uuid.Nil, fmt.Errorf("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	jUUID, err := uuid.FromString(parts[1])
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return createdAt, jUUID, nil
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"github.com/satori/go.uuid"
+)
+
+// PaymentTaskKind is the kind of money movement a payment task performs
+type PaymentTaskKind string
+
+// Payment task kinds
+const (
+	PaymentTaskChargeUser  PaymentTaskKind = "charge_user"
+	PaymentTaskPayMiner    PaymentTaskKind = "pay_miner"
+	PaymentTaskChargeMiner PaymentTaskKind = "charge_miner"
+	// PaymentTaskRechargeCredit tops up the job owner's credit if its charge left it below
+	// their auto-recharge threshold
+	PaymentTaskRechargeCredit PaymentTaskKind = "recharge_credit"
+	// PaymentTaskReleaseCollateral pays the miner the collateral held back from the job's payout
+	// once its hold period is over
+	PaymentTaskReleaseCollateral PaymentTaskKind = "release_collateral"
+)
+
+// PaymentTask is a queued money movement for a job. Attempts counts the current one
+type PaymentTask struct {
+	ID       int64
+	Kind     PaymentTaskKind
+	Job      uuid.UUID
+	Attempts int
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ErrProjectExists is returned when renaming a project to a name the user already has
+var ErrProjectExists = errors.New("project already exists")
+
+// RenameProject renames user uUUID's project to name. Returns sql.ErrNoRows if the user has
+// no such project, ErrProjectActive if any of its jobs haven't ended and ErrProjectExists
+// if name is taken
+func RenameProject(r *http.Request, uUUID uuid.UUID, project, name string) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		pUUID := uuid.UUID{}
+		sqlStmt := `
+	SELECT uuid
+	FROM projects
+	WHERE (name, user_uuid) = ($1, $2) AND
+		deleted_at IS NULL AND
+		org_uuid IS NULL
+	FOR UPDATE
+	`
+		if err := tx.QueryRow(sqlStmt, project, uUUID).Scan(&pUUID); err != nil {
+			return "error querying for project", err
+		}
+
+		var n int64
+		sqlStmt = `
+	SELECT COUNT(*)
+	FROM jobs
+	WHERE project_uuid = $1 AND
+		state NOT IN ('finished', 'canceled', 'failed')
+	`
+		if err := tx.QueryRow(sqlStmt, pUUID).Scan(&n); err != nil {
+			return "error querying for project active jobs", err
+		} else if n > 0 {
+			return "project has active jobs", ErrProjectActive
+		}
+
+		sqlStmt = `
+	UPDATE projects
+	SET name = $2,
+	busy = NULL
+	WHERE uuid = $1
+	`
+		if _, err := tx.Exec(sqlStmt, pUUID, name); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == errUniqueViolationCode {
+				return "project name already exists", ErrProjectExists
+			}
+			return "error renaming project", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		if err == sql.ErrNoRows || err == ErrProjectActive || err == ErrProjectExists {
+			return err
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+			)
+		}
+		return err
+	}
+	return nil
+}
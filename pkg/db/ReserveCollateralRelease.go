@@ -0,0 +1,80 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// ReserveCollateralRelease marks the collateral still held back from job jUUID's payout as
+// released, so penalties can no longer take it, and returns the miner, the amount (cents) to pay
+// them and the release's transfer id ("" if it hasn't been paid yet). Calling it again returns
+// the original amount
+func ReserveCollateralRelease(jUUID uuid.UUID) (uuid.UUID, int64, string, error) {
+	var mUUID uuid.UUID
+	var amount int64
+	var transferID string
+	tx, txerr := db.Begin()
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		var released bool
+		sqlStmt := `
+		SELECT miner_uuid, released_at IS NOT NULL, COALESCE(released_amt, 0), COALESCE(released_id, '')
+		FROM miner_collateral
+		WHERE job_uuid = $1
+		FOR UPDATE
+		`
+		if err := tx.QueryRow(sqlStmt, jUUID).Scan(&mUUID, &released, &amount, &transferID); err != nil {
+			return "error querying for miner collateral", err
+		}
+		if released {
+			return "", tx.Commit()
+		}
+
+		sqlStmt = `
+		UPDATE miner_collateral
+		SET released_at = NOW(),
+		released_amt = remaining,
+		remaining = 0
+		WHERE job_uuid = $1
+		RETURNING released_amt
+		`
+		if err := tx.QueryRow(sqlStmt, jUUID).Scan(&amount); err != nil {
+			return "error updating miner collateral released", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return uuid.Nil, 0, "", err
+	}
+
+	return mUUID, amount, transferID, nil
+}
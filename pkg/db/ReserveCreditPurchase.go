@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ErrCreditPurchaseExists is returned when a credit purchase's id is already used by another
+// account's purchase, or an auto-recharge has already been recorded for its job
+var ErrCreditPurchaseExists = errors.New("credit purchase already recorded")
+
+// CreditPurchase is a pack of prepaid credit bought by an account. Price and Credit are in cents.
+// TriggerJob is the job whose charge set off an auto-recharge, or uuid.Nil if the user bought it
+type CreditPurchase struct {
+	UUID       uuid.UUID
+	Account    uuid.UUID
+	Pack       string
+	Price      int64
+	Credit     int64
+	StripeID   string
+	TriggerJob uuid.UUID
+}
+
+// ReserveCreditPurchase records credit purchase cp as pending before its card is charged. The
+// account is locked while check, if not nil, is passed its credit plus that of its purchases
+// pending from the last day, so concurrent purchases see each other. If cp.UUID has already been
+// recorded for the same account, cp is filled in from it instead, and finalized reports whether
+// it's been charged. Errors returned by check are returned as is, unlogged
+func ReserveCreditPurchase(r *http.Request, cp *CreditPurchase, check func(credit int64) error) (bool, error) {
+	var checkErr error
+	var finalized bool
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		var credit int64
+		sqlStmt := `
+		SELECT credit
+		FROM accounts
+		WHERE uuid = $1
+		FOR UPDATE
+		`
+		if err := tx.QueryRow(sqlStmt, cp.Account).Scan(&credit); err != nil {
+			return "error querying for account credit", err
+		}
+
+		aUUID := uuid.UUID{}
+		triggerJob := uuid.NullUUID{}
+		sqlStmt = `
+		SELECT account_uuid, pack, price, credit, COALESCE(stripe_id, ''), trigger_job_uuid,
+			finalized_at IS NOT NULL
+		FROM credit_purchases
+		WHERE uuid = $1
+		`
+		if err := tx.QueryRow(sqlStmt, cp.UUID).Scan(&aUUID, &cp.Pack, &cp.Price, &cp.Credit, &cp.StripeID,
+			&triggerJob, &finalized); err == nil {
+			if !uuid.Equal(aUUID, cp.Account) {
+				return "credit purchase id is already in use", ErrCreditPurchaseExists
+			}
+			cp.TriggerJob = triggerJob.UUID
+			if err := tx.Commit(); err != nil {
+				return errCommitTx, err
+			}
+			return "", nil
+		} else if err != sql.ErrNoRows {
+			return "error querying for credit purchase", err
+		}
+
+		if check != nil {
+			var pending int64
+			sqlStmt = `
+		SELECT COALESCE(SUM(credit), 0)::bigint
+		FROM credit_purchases
+		WHERE account_uuid = $1 AND
+			finalized_at IS NULL AND
+			created_at > NOW() - INTERVAL '1 day'
+		`
+			if err := tx.QueryRow(sqlStmt, cp.Account).Scan(&pending); err != nil {
+				return "error querying for pending credit purchases", err
+			}
+			if checkErr = check(credit + pending); checkErr != nil {
+				return "credit purchase not needed", checkErr
+			}
+		}
+
+		sqlStmt = `
+		INSERT INTO credit_purchases (uuid, account_uuid, pack, price, credit, trigger_job_uuid)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		`
+		if _, err := tx.Exec(sqlStmt, cp.UUID, cp.Account, cp.Pack, cp.Price, cp.Credit,
+			uuid.NullUUID{UUID: cp.TriggerJob, Valid: !uuid.Equal(cp.TriggerJob, uuid.Nil)}); err != nil {
+			return "error inserting pending credit purchase", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		if checkErr != nil || err == ErrCreditPurchaseExists {
+			return false, err
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok && pqErr.Code == errUniqueViolationCode {
+			return false, ErrCreditPurchaseExists
+		}
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"cpID", cp.UUID,
+				"aID", cp.Account,
+				"jID", cp.TriggerJob,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"cpID", cp.UUID,
+				"aID", cp.Account,
+				"jID", cp.TriggerJob,
+			)
+		}
+		return false, err
+	}
+	return finalized, nil
+}
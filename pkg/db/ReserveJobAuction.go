@@ -0,0 +1,102 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ReserveJobAuction runs check against the quota of organization oUUID or, if it's uuid.Nil,
+// of account aUUID's personal projects and, if it passes, reserves job jUUID for auction so it
+// counts as concurrent until auctioned or auctionReserveWindow passes. The account or
+// organization is locked throughout, so concurrent auctions can't all pass the same check.
+// Errors returned by check are returned as is, unlogged
+func ReserveJobAuction(r *http.Request, aUUID, oUUID, jUUID uuid.UUID, check func(*Quota) error) error {
+	var checkErr error
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
+	SELECT uuid
+	FROM accounts
+	WHERE uuid = $1
+	FOR UPDATE
+	`
+		lockUUID := aUUID
+		if !uuid.Equal(oUUID, uuid.Nil) {
+			sqlStmt = `
+	SELECT uuid
+	FROM orgs
+	WHERE uuid = $1
+	FOR UPDATE
+	`
+			lockUUID = oUUID
+		}
+		if err := tx.QueryRow(sqlStmt, lockUUID).Scan(&lockUUID); err != nil {
+			return "error locking quota owner", err
+		}
+
+		q := newQuota()
+		if message, err := queryQuota(tx, q, aUUID, oUUID, jUUID); err != nil {
+			return message, err
+		}
+		if checkErr = check(q); checkErr != nil {
+			return "quota exceeded", checkErr
+		}
+
+		sqlStmt = `
+	UPDATE jobs
+	SET auction_reserved_at = NOW()
+	WHERE uuid = $1
+	`
+		if _, err := tx.Exec(sqlStmt, jUUID); err != nil {
+			return "error updating jobs auction_reserved_at", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		if checkErr != nil {
+			return checkErr
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"oID", oUUID,
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"oID", oUUID,
+				"jID", jUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
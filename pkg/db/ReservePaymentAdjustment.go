@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ErrPaymentAdjustmentIDInUse is returned when an adjustment's id is already used by a different
+// job's or kind of adjustment
+var ErrPaymentAdjustmentIDInUse = errors.New("adjustment id is already in use")
+
+// ReservePaymentAdjustment records refund or transfer reversal adj as pending before stripe is
+// called, so it counts against what's left of job adj.Job's charge or transfer. The job's
+// payments row is locked while check validates adj against its refund info, so concurrent
+// adjustments can't both pass. If adj.UUID has already been recorded for the same job and kind,
+// adj is filled in from it instead, and finalized reports whether it's been made. Errors returned
+// by check are returned as is, unlogged
+func ReservePaymentAdjustment(r *http.Request, adj *PaymentAdjustment,
+	check func(*JobRefundInfo) error) (bool, error) {
+	var checkErr error
+	var finalized bool
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		ri := &JobRefundInfo{}
+		if err := tx.QueryRow(jobRefundInfoSQL+"FOR UPDATE OF p", adj.Job).Scan(&ri.UserCharged, &ri.Charged,
+			&ri.Refunded, &ri.MinerPaid, &ri.MinerTransferID, &ri.Transferred, &ri.Reversed); err != nil {
+			return "error querying for job refund info", err
+		}
+
+		var kind PaymentAdjustmentKind
+		jUUID := uuid.NullUUID{}
+		sqlStmt := `
+		SELECT kind, job_uuid, account_uuid, amount, method, COALESCE(stripe_id, ''), reason, admin_uuid,
+			finalized_at IS NOT NULL
+		FROM payment_adjustments
+		WHERE uuid = $1
+		`
+		if err := tx.QueryRow(sqlStmt, adj.UUID).Scan(&kind, &jUUID, &adj.Account, &adj.Amount, &adj.Method,
+			&adj.StripeID, &adj.Reason, &adj.Admin, &finalized); err == nil {
+			if kind != adj.Kind || !uuid.Equal(jUUID.UUID, adj.Job) {
+				return "adjustment id is already in use", ErrPaymentAdjustmentIDInUse
+			}
+			if err := tx.Commit(); err != nil {
+				return errCommitTx, err
+			}
+			return "", nil
+		} else if err != sql.ErrNoRows {
+			return "error querying for payment adjustment", err
+		}
+
+		if checkErr = check(ri); checkErr != nil {
+			return "invalid payment adjustment", checkErr
+		}
+
+		sqlStmt = `
+		INSERT INTO payment_adjustments (uuid, kind, job_uuid, account_uuid, amount, method, reason, admin_uuid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+		if _, err := tx.Exec(sqlStmt, adj.UUID, adj.Kind, adj.Job, adj.Account,
+			adj.Amount, adj.Method, adj.Reason, adj.Admin); err != nil {
+			return "error inserting pending payment adjustment", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		if checkErr != nil || err == ErrPaymentAdjustmentIDInUse {
+			return false, err
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adj.UUID,
+				"jID", adj.Job,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adj.UUID,
+				"jID", adj.Job,
+			)
+		}
+		return false, err
+	}
+	return finalized, nil
+}
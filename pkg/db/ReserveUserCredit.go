@@ -0,0 +1,107 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// ReserveUserCredit takes up to amount (cents) of account aUUID's credit towards job jUUID's
+// charge and returns how much it took. The account row is locked while credit is deducted, so
+// concurrent charges can't spend the same credit, and the reservation is recorded on the job's
+// payments row in the same transaction, so calling it again returns the original reservation.
+// If any credit is taken, a check whether the account needs recharging is queued
+func ReserveUserCredit(jUUID, aUUID uuid.UUID, amount int64) (int64, error) {
+	var credit int64
+	tx, txerr := db.Begin()
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		var reserved bool
+		sqlStmt := `
+		SELECT user_credit_reserved_at IS NOT NULL, COALESCE(user_charged_credit, 0)
+		FROM payments
+		WHERE job_uuid = $1
+		FOR UPDATE
+		`
+		if err := tx.QueryRow(sqlStmt, jUUID).Scan(&reserved, &credit); err != nil {
+			return "error querying for payments credit reservation", err
+		}
+		if reserved {
+			return "", tx.Commit()
+		}
+
+		sqlStmt = `
+		WITH a AS (
+			SELECT uuid, LEAST(GREATEST(credit, 0), $2) AS taken
+			FROM accounts
+			WHERE uuid = $1
+			FOR UPDATE
+		)
+		UPDATE accounts
+		SET credit = accounts.credit - a.taken
+		FROM a
+		WHERE accounts.uuid = a.uuid
+		RETURNING a.taken
+		`
+		if err := tx.QueryRow(sqlStmt, aUUID, amount).Scan(&credit); err != nil {
+			return "error updating account credit", err
+		}
+
+		sqlStmt = `
+		UPDATE payments
+		SET user_credit_reserved_at = NOW(),
+		user_charged_credit = $2
+		WHERE job_uuid = $1
+		`
+		if _, err := tx.Exec(sqlStmt, jUUID, credit); err != nil {
+			return "error updating payments credit reservation", err
+		}
+
+		if credit > 0 {
+			sqlStmt = `
+		INSERT INTO payment_tasks (kind, job_uuid)
+		VALUES ($1, $2)
+		ON CONFLICT (kind, job_uuid) DO NOTHING
+		`
+			if _, err := tx.Exec(sqlStmt, PaymentTaskRechargeCredit, jUUID); err != nil {
+				return "error inserting recharge credit payment task", err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"aID", aUUID,
+			)
+		}
+		return 0, err
+	}
+
+	return credit, nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// SetAccountNotifyEmail sets whether account aUUID receives job notification emails
+func SetAccountNotifyEmail(r *http.Request, aUUID uuid.UUID, notify bool) error {
+	sqlStmt := `
+	UPDATE accounts
+	SET notify_email = $2
+	WHERE uuid = $1
+	`
+	if _, err := db.Exec(sqlStmt, aUUID, notify); err != nil {
+		message := "error updating account notify_email"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
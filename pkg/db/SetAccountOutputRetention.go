@@ -0,0 +1,52 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// SetAccountOutputRetention sets the account aUUID's output retention days and max bytes (0 for
+// unlimited). Either left nil is unchanged
+func SetAccountOutputRetention(r *http.Request, aUUID uuid.UUID, days, maxBytes *int64) error {
+	sqlStmt := `
+	UPDATE accounts
+	SET output_retention_days = CASE WHEN $2 THEN $3 ELSE output_retention_days END,
+	output_retention_bytes = CASE WHEN $4 THEN $5 ELSE output_retention_bytes END
+	WHERE uuid = $1
+	`
+	var d, b sql.NullInt64
+	if days != nil {
+		d = sql.NullInt64{Int64: *days, Valid: *days > 0}
+	}
+	if maxBytes != nil {
+		b = sql.NullInt64{Int64: *maxBytes, Valid: *maxBytes > 0}
+	}
+	if _, err := db.Exec(sqlStmt, aUUID, days != nil, d, maxBytes != nil, b); err != nil {
+		message := "error updating account output retention"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// SetAutoRecharge sets account aUUID to buy credit pack automatically whenever its credit falls
+// below threshold (cents). An empty pack turns auto-recharge off
+func SetAutoRecharge(r *http.Request, aUUID uuid.UUID, pack string, threshold int64) error {
+	sqlStmt := `
+	UPDATE accounts
+	SET auto_recharge_pack = NULLIF($2, ''),
+		auto_recharge_threshold = CASE WHEN $2 = '' THEN NULL ELSE $3::bigint END
+	WHERE uuid = $1
+	`
+	if _, err := db.Exec(sqlStmt, aUUID, pack, threshold); err != nil {
+		message := "error updating account auto-recharge"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"aID", aUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
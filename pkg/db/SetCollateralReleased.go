@@ -0,0 +1,62 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// SetCollateralReleased records the transfer paying out job jUUID's released collateral and
+// ledger transaction lt, atomically
+func SetCollateralReleased(jUUID uuid.UUID, transferID string, lt *LedgerTxn) error {
+	tx, txerr := db.Begin()
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
+		UPDATE miner_collateral
+		SET released_id = $2
+		WHERE job_uuid = $1
+		`
+		if _, err := tx.Exec(sqlStmt, jUUID, transferID); err != nil {
+			return "error updating miner collateral released id", err
+		}
+
+		if err := insertLedgerTxn(tx, lt); err != nil {
+			return "error inserting ledger txn", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// SetCustomerPaymentSuspended stops (or lets) the account billed to stripe customer customerID
+// post jobs for a failed payment and returns whether it changed, recording the change as the
+// latest so stripe events from before it are skipped. Payment suspension is separate from
+// account suspension, so the user can still log in to update their payment info. Org customers
+// are left alone
+func SetCustomerPaymentSuspended(r *http.Request, customerID string, suspended bool) (bool, error) {
+	sqlStmt := `
+	UPDATE accounts
+	SET payment_suspended = $2,
+	payment_suspension_at = NOW()
+	WHERE stripe_customer_id = $1 AND
+		payment_suspended <> $2
+	`
+	res, err := db.Exec(sqlStmt, customerID, suspended)
+	if err != nil {
+		message := "error updating account payment suspended"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"customerID", customerID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"customerID", customerID,
+			)
+		}
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Sugar.Errorw("error getting rows affected",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"customerID", customerID,
+		)
+		return false, err
+	}
+	return n > 0, nil
+}
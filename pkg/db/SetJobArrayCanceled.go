@@ -0,0 +1,43 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// SetJobArrayCanceled sets job array arrUUID's canceled_at
+func SetJobArrayCanceled(r *http.Request, arrUUID uuid.UUID) error {
+	sqlStmt := `
+	UPDATE job_arrays
+	SET canceled_at = NOW()
+	WHERE uuid = $1 AND
+		canceled_at IS NULL
+	`
+	if _, err := db.Exec(sqlStmt, arrUUID); err != nil {
+		message := "error updating job_arrays.canceled_at"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"arrID", arrUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"arrID", arrUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// SetJobArrayMember records job jUUID as member index of job array arrUUID, run with args
+func SetJobArrayMember(r *http.Request, jUUID, arrUUID uuid.UUID, index int, args []string) error {
+	sqlStmt := `
+	UPDATE jobs
+	SET array_uuid = $2,
+		array_index = $3,
+		array_args = $4
+	WHERE uuid = $1
+	`
+	if _, err := db.Exec(sqlStmt, jUUID, arrUUID, index, pq.Array(args)); err != nil {
+		message := "error updating job array membership"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
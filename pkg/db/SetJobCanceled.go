@@ -7,26 +7,62 @@ import (
 	"net/http"
 )
 
-// SetJobCanceled sets job canceled_at and active=false for job jUUID
-func SetJobCanceled(r *http.Request, jUUID uuid.UUID) error {
-	sqlStmt := `
+// SetJobCanceled sets job canceled_at and state=canceled for job jUUID and, if its auction
+// had completed, queues payment tasks of kinds; before that there's no rate to pay. Returns
+// ErrInvalidJobStateTransition if the job has already ended
+func SetJobCanceled(r *http.Request, jUUID uuid.UUID, kinds ...PaymentTaskKind) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		if err := transitionJobState(tx, jUUID, JobStateCanceled); err != nil {
+			return "error transitioning job state to canceled", err
+		}
+
+		sqlStmt := `
 		UPDATE jobs j
-		SET canceled_at = NOW(),
-		active = false
-		FROM users u, projects proj, miners m, bids b
+		SET canceled_at = NOW()
 		WHERE j.uuid = $1 AND
-			j.canceled_at IS NULL AND
-			proj.uuid = j.project_uuid AND
-			u.uuid = proj.user_uuid AND (
-			j.win_bid_uuid IS NULL OR (
-			b.uuid = j.win_bid_uuid AND
-			m.uuid = b.miner_uuid
-			))
+			j.canceled_at IS NULL
 		`
-	if _, err := db.Exec(sqlStmt, jUUID); err != nil {
-		message := "error updating jobs canceled_at, active"
+		if _, err := tx.Exec(sqlStmt, jUUID); err != nil {
+			return "error updating jobs canceled_at", err
+		}
+
+		if len(kinds) > 0 {
+			var auctionCompleted bool
+			sqlStmt = `
+		SELECT s.auction_completed IS NOT NULL
+		FROM statuses s
+		WHERE s.job_uuid = $1
+		`
+			if err := tx.QueryRow(sqlStmt, jUUID).Scan(&auctionCompleted); err != nil {
+				return "error querying for statuses auction_completed", err
+			}
+			if auctionCompleted {
+				if err := insertPaymentTasks(tx, jUUID, kinds); err != nil {
+					return "error inserting payment tasks", err
+				}
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
 		pqErr, ok := err.(*pq.Error)
-		if ok {
+		if err == ErrInvalidJobStateTransition {
+			log.Sugar.Infow("job already ended, not canceling",
+				"method", r.Method,
+				"url", r.URL,
+				"jID", jUUID,
+			)
+		} else if ok {
 			log.Sugar.Errorw(message,
 				"method", r.Method,
 				"url", r.URL,
@@ -45,8 +81,12 @@ func SetJobCanceled(r *http.Request, jUUID uuid.UUID) error {
 				"jID", jUUID,
 			)
 		}
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
 		return err
 	}
-
 	return nil
 }
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// SetJobDataFingerprint records the fingerprint of the data set job jUUID synced
+func SetJobDataFingerprint(r *http.Request, jUUID uuid.UUID, fp string) error {
+	sqlStmt := `
+	UPDATE jobs
+	SET data_fingerprint = $2
+	WHERE uuid = $1
+	`
+	if _, err := db.Exec(sqlStmt, jUUID, fp); err != nil {
+		message := "error updating jobs.data_fingerprint"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -1,52 +1,72 @@
 package db
 
 import (
+	"context"
 	"github.com/lib/pq"
 	"github.com/satori/go.uuid"
 	"github.com/wminshew/emrysserver/pkg/log"
 )
 
-// https://github.com/lib/pq/blob/master/error.go#L78
-const errCheckViolation = "23514" // "check_violation"
+// SetJobFailed sets job failed_at and state=failed for job jUUID, and queues payment tasks
+// of kinds. Returns ErrInvalidJobStateTransition if the job has already ended or hasn't been auctioned
+func SetJobFailed(jUUID uuid.UUID, kinds ...PaymentTaskKind) error {
+	ctx := context.Background()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		if err := transitionJobState(tx, jUUID, JobStateFailed); err != nil {
+			return "error transitioning job state to failed", err
+		}
 
-// SetJobFailed sets job failed_at and active=false for job jUUID
-func SetJobFailed(jUUID uuid.UUID) error {
-	sqlStmt := `
+		sqlStmt := `
 		UPDATE jobs j
-		SET failed_at = NOW(),
-		active = false
-		FROM miners m, bids b
+		SET failed_at = NOW()
 		WHERE j.uuid = $1 AND
-			j.failed_at IS NULL AND
-			b.uuid = j.win_bid_uuid AND
-			m.uuid = b.miner_uuid
+			j.failed_at IS NULL
 		`
-	if _, err := db.Exec(sqlStmt, jUUID); err != nil {
-		message := "error updating jobs failed_at, active"
+		if _, err := tx.Exec(sqlStmt, jUUID); err != nil {
+			return "error updating jobs failed_at", err
+		}
+
+		if err := insertPaymentTasks(tx, jUUID, kinds); err != nil {
+			return "error inserting payment tasks", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
 		pqErr, ok := err.(*pq.Error)
-		if ok {
-			if pqErr.Code == errCheckViolation {
-				log.Sugar.Infow("Job already ended, not updating failed_at",
-					"jID", jUUID,
-				)
-			} else {
-				log.Sugar.Errorw(message,
-					"err", err.Error(),
-					"jID", jUUID,
-					"pq_sev", pqErr.Severity,
-					"pq_code", pqErr.Code,
+		if err == ErrInvalidJobStateTransition {
+			log.Sugar.Infow("job already ended, not updating failed_at",
+				"jID", jUUID,
+			)
+		} else if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
 				"pq_msg", pqErr.Message,
-					"pq_detail", pqErr.Detail,
-				)
-			}
+				"pq_detail", pqErr.Detail,
+			)
 		} else {
 			log.Sugar.Errorw(message,
 				"err", err.Error(),
 				"jID", jUUID,
 			)
 		}
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
 		return err
 	}
-
 	return nil
 }
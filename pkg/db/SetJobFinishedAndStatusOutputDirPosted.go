@@ -8,9 +8,10 @@ import (
 )
 
 // SetJobFinishedAndStatusOutputDataPosted sets job completed
-// (and inactive) and status for job jUUID
+// (and finished) and status for job jUUID, and queues payment tasks of kinds.
+// Returns ErrInvalidJobStateTransition if the job has already ended or isn't uploading
 func SetJobFinishedAndStatusOutputDataPosted(r *http.Request,
-	jUUID uuid.UUID) error {
+	jUUID uuid.UUID, kinds ...PaymentTaskKind) error {
 	ctx := r.Context()
 	tx, txerr := db.BeginTx(ctx, nil)
 	if message, err := func() (string, error) {
@@ -18,11 +19,14 @@ func SetJobFinishedAndStatusOutputDataPosted(r *http.Request,
 			return errBeginTx, txerr
 		}
 
+		if err := transitionJobState(tx, jUUID, JobStateFinished); err != nil {
+			return "error transitioning job state to finished", err
+		}
+
 		completedAt := pq.NullTime{}
 		sqlStmt := `
 		UPDATE jobs j
-		SET completed_at = NOW(),
-		active = false
+		SET completed_at = NOW()
 		FROM users u, projects proj, miners m, bids b
 		WHERE j.uuid = $1 AND
 			j.completed_at IS NULL AND
@@ -33,7 +37,7 @@ func SetJobFinishedAndStatusOutputDataPosted(r *http.Request,
 		RETURNING j.completed_at
 		`
 		if err := tx.QueryRow(sqlStmt, jUUID).Scan(&completedAt); err != nil {
-			return "error updating jobs completed_at, active", err
+			return "error updating jobs completed_at", err
 		}
 
 		if completedAt.Valid {
@@ -43,7 +47,7 @@ func SetJobFinishedAndStatusOutputDataPosted(r *http.Request,
 			WHERE s.job_uuid = $1 AND
 				s.output_data_posted IS NULL
 			`
-			if _, err := db.Exec(sqlStmt, jUUID, completedAt.Time); err != nil {
+			if _, err := tx.Exec(sqlStmt, jUUID, completedAt.Time); err != nil {
 				return "error updating statuses output_data_posted", err
 			}
 		} else {
@@ -51,6 +55,10 @@ func SetJobFinishedAndStatusOutputDataPosted(r *http.Request,
 			log.Sugar.Errorf("null completedAt")
 		}
 
+		if err := insertPaymentTasks(tx, jUUID, kinds); err != nil {
+			return "error inserting payment tasks", err
+		}
+
 		if err := tx.Commit(); err != nil {
 			return errCommitTx, err
 		}
@@ -58,7 +66,13 @@ func SetJobFinishedAndStatusOutputDataPosted(r *http.Request,
 		return "", nil
 	}(); err != nil {
 		pqErr, ok := err.(*pq.Error)
-		if ok {
+		if err == ErrInvalidJobStateTransition {
+			log.Sugar.Infow("job can't be finished from its current state",
+				"method", r.Method,
+				"url", r.URL,
+				"jID", jUUID,
+			)
+		} else if ok {
 			log.Sugar.Errorw(message,
 				"method", r.Method,
 				"url", r.URL,
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// SetJobHeartbeat records that job jUUID's miner reported it still running
+func SetJobHeartbeat(jUUID uuid.UUID) error {
+	sqlStmt := `
+	UPDATE jobs
+	SET last_heartbeat_at = NOW()
+	WHERE uuid = $1 AND
+		state IN ('running', 'uploading')
+	`
+	if _, err := db.Exec(sqlStmt, jUUID); err != nil {
+		message := "error updating job heartbeat"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"errors"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ErrJobMetadataFull is returned when an edit would leave a job with more metadata keys than allowed
+var ErrJobMetadataFull = errors.New("job metadata full")
+
+// SetJobLabels edits job jUUID's labels. name and tags replace the current values unless nil
+// (an empty name clears it); setMetadata is merged into the metadata and deleteMetadata keys
+// are then removed. Returns ErrJobMetadataFull if more than maxMetadata keys would remain
+func SetJobLabels(r *http.Request, jUUID uuid.UUID, name *string, tags *[]string,
+	setMetadata map[string]string, deleteMetadata []string, maxMetadata int) error {
+	var newName string
+	if name != nil {
+		newName = *name
+	}
+	var newTags []string
+	if tags != nil {
+		newTags = *tags
+	}
+	metadata, err := metadataJSON(setMetadata)
+	if err != nil {
+		return err
+	}
+	sqlStmt := `
+	UPDATE jobs
+	SET name = CASE WHEN $2 THEN NULLIF($3, '') ELSE name END,
+		tags = CASE WHEN $4 THEN COALESCE($5, '{}') ELSE tags END,
+		metadata = (metadata || $6::jsonb) - COALESCE($7::text[], '{}')
+	WHERE uuid = $1 AND
+		(SELECT COUNT(*) FROM jsonb_object_keys((metadata || $6::jsonb) - COALESCE($7::text[], '{}'))) <= $8
+	`
+	res, err := db.Exec(sqlStmt, jUUID, name != nil, newName, tags != nil, pq.Array(newTags),
+		string(metadata), pq.Array(deleteMetadata), maxMetadata)
+	if err != nil {
+		message := "error updating job labels"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrJobMetadataFull
+	}
+
+	return nil
+}
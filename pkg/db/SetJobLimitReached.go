@@ -0,0 +1,39 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// SetJobLimitReached sets limit_reached_at=NOW() for job jUUID
+func SetJobLimitReached(jUUID uuid.UUID) error {
+	sqlStmt := `
+	UPDATE jobs
+	SET limit_reached_at = NOW()
+	WHERE uuid = $1 AND
+		limit_reached_at IS NULL
+	`
+	if _, err := db.Exec(sqlStmt, jUUID); err != nil {
+		message := "error updating jobs limit_reached_at"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -8,7 +8,7 @@ import (
 	"net/http"
 )
 
-// SetJobWinnerAndAuctionStatus sets the winBid UUID, pay rate, and status for job jUUID
+// SetJobWinnerAndAuctionStatus sets the winBid UUID, pay rate, status and state for job jUUID
 func SetJobWinnerAndAuctionStatus(r *http.Request, jUUID, wbUUID uuid.UUID, payRate float64) *app.Error {
 	ctx := r.Context()
 	tx, txerr := db.BeginTx(ctx, nil)
@@ -38,6 +38,10 @@ func SetJobWinnerAndAuctionStatus(r *http.Request, jUUID, wbUUID uuid.UUID, payR
 			return "error updating job status", err
 		}
 
+		if err := advanceJobState(tx, jUUID); err != nil {
+			return "error advancing job state", err
+		}
+
 		if err := tx.Commit(); err != nil {
 			return errCommitTx, err
 		}
@@ -15,7 +15,7 @@ func SetMinerPaid(mUUID uuid.UUID) error {
 	SET p.miner_paid = NOW()
 	WHERE b.miner_uuid = $1 AND
 		p.miner_paid IS NULL AND
-		j.active = false
+		j.state IN ('finished', 'canceled', 'failed')
 	`
 	if _, err := db.Exec(sqlStmt, mUUID); err != nil {
 		message := "error updating miner payments"
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// SetOrgStripeSubscriptionID sets organization oUUID's stripe subscription id
+func SetOrgStripeSubscriptionID(r *http.Request, oUUID uuid.UUID, stripeSubscriptionID string) error {
+	sqlStmt := `
+	UPDATE orgs
+	SET stripe_subscription_id = $2
+	WHERE uuid = $1
+	`
+	if _, err := db.Exec(sqlStmt, oUUID, stripeSubscriptionID); err != nil {
+		message := "error updating org stripe subscription id"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"oID", oUUID,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// SetPaymentTaskDone marks payment task id done
+func SetPaymentTaskDone(id int64) error {
+	sqlStmt := `
+	UPDATE payment_tasks
+	SET state = 'done',
+		locked_until = NULL,
+		updated_at = NOW()
+	WHERE id = $1
+	`
+	if _, err := db.Exec(sqlStmt, id); err != nil {
+		message := "error setting payment task done"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"taskID", id,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"taskID", id,
+			)
+		}
+		return err
+	}
+	return nil
+}
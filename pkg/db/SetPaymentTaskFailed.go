@@ -0,0 +1,42 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+// SetPaymentTaskFailed records payment task id's failed attempt and either schedules it to
+// retry at retryAt or, if dead, dead-letters it for manual review
+func SetPaymentTaskFailed(id int64, errMsg string, retryAt time.Time, dead bool) error {
+	sqlStmt := `
+	UPDATE payment_tasks
+	SET state = CASE WHEN $4 THEN 'dead' ELSE 'pending' END,
+		last_error = $2,
+		run_at = $3,
+		locked_until = NULL,
+		updated_at = NOW()
+	WHERE id = $1
+	`
+	if _, err := db.Exec(sqlStmt, id, errMsg, retryAt, dead); err != nil {
+		message := "error setting payment task failed"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"taskID", id,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"taskID", id,
+			)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// SetPaymentsCollateralHold records that hold (cents) of job jUUID's miner payout is held back as
+// collateral and returns the recorded hold. Once recorded it doesn't change, so a retried payout
+// transfers the same amount
+func SetPaymentsCollateralHold(jUUID uuid.UUID, hold int64) (int64, error) {
+	sqlStmt := `
+	UPDATE payments
+	SET miner_collateral_held = COALESCE(miner_collateral_held, $2)
+	WHERE job_uuid = $1
+	RETURNING miner_collateral_held
+	`
+	if err := db.QueryRow(sqlStmt, jUUID, hold).Scan(&hold); err != nil {
+		message := "error updating payments collateral hold"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return 0, err
+	}
+	return hold, nil
+}
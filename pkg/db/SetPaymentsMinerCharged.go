@@ -6,17 +6,42 @@ import (
 	"github.com/wminshew/emrysserver/pkg/log"
 )
 
-// SetPaymentsMinerCharged sets payments miner charged
-func SetPaymentsMinerCharged(jUUID uuid.UUID, chargeID string, jobAmount int64) error {
-	sqlStmt := `
+// SetPaymentsMinerCharged sets payments miner charged and records ledger transaction lt, if any, atomically
+func SetPaymentsMinerCharged(jUUID uuid.UUID, chargeID string, jobAmount int64, lt *LedgerTxn) error {
+	tx, txerr := db.Begin()
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
 		UPDATE payments
 		SET miner_charged_at = NOW(),
 		miner_charged_id = $2,
 		miner_charged_amt = $3
 		WHERE job_uuid = $1
 		`
-	if _, err := db.Exec(sqlStmt, jUUID, chargeID, jobAmount); err != nil {
-		message := "error updating payments miner charged"
+		if _, err := tx.Exec(sqlStmt, jUUID, chargeID, jobAmount); err != nil {
+			return "error updating payments miner charged", err
+		}
+
+		if lt != nil {
+			if err := insertLedgerTxn(tx, lt); err != nil {
+				return "error inserting ledger txn", err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			log.Sugar.Errorw(message,
@@ -4,19 +4,70 @@ import (
 	"github.com/lib/pq"
 	"github.com/satori/go.uuid"
 	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
 )
 
-// SetPaymentsMinerPaid sets payments miner paid
-func SetPaymentsMinerPaid(jUUID uuid.UUID, transferID string, jobAmount int64) error {
-	sqlStmt := `
+// CollateralHold is the part (cents) of a miner's payout held back as collateral until ReleaseAt
+type CollateralHold struct {
+	Miner     uuid.UUID
+	Amount    int64
+	ReleaseAt time.Time
+}
+
+// SetPaymentsMinerPaid sets payments miner paid, holds back collateral ch (if any) and records
+// ledger transaction lt, atomically. The collateral's release is queued for ch.ReleaseAt
+func SetPaymentsMinerPaid(jUUID uuid.UUID, transferID string, jobAmount int64, ch *CollateralHold, lt *LedgerTxn) error {
+	tx, txerr := db.Begin()
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
 		UPDATE payments
 		SET miner_paid_at = NOW(),
 		miner_paid_id = $2,
 		miner_paid_amt = $3
 		WHERE job_uuid = $1
 		`
-	if _, err := db.Exec(sqlStmt, jUUID, transferID, jobAmount); err != nil {
-		message := "error updating payments miner paid"
+		if _, err := tx.Exec(sqlStmt, jUUID, transferID, jobAmount); err != nil {
+			return "error updating payments miner paid", err
+		}
+
+		if ch != nil && ch.Amount > 0 {
+			sqlStmt = `
+		INSERT INTO miner_collateral (job_uuid, miner_uuid, amount, remaining, release_at)
+		VALUES ($1, $2, $3, $3, $4)
+		`
+			if _, err := tx.Exec(sqlStmt, jUUID, ch.Miner, ch.Amount, ch.ReleaseAt); err != nil {
+				return "error inserting miner collateral", err
+			}
+
+			sqlStmt = `
+		INSERT INTO payment_tasks (kind, job_uuid, run_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (kind, job_uuid) DO NOTHING
+		`
+			if _, err := tx.Exec(sqlStmt, PaymentTaskReleaseCollateral, jUUID, ch.ReleaseAt); err != nil {
+				return "error inserting release collateral payment task", err
+			}
+		}
+
+		if err := insertLedgerTxn(tx, lt); err != nil {
+			return "error inserting ledger txn", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			log.Sugar.Errorw(message,
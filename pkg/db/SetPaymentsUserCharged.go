@@ -6,18 +6,41 @@ import (
 	"github.com/wminshew/emrysserver/pkg/log"
 )
 
-// SetPaymentsUserCharged sets payments user charged
-func SetPaymentsUserCharged(jUUID uuid.UUID, invoiceID string, chargeAmount, credit int64) error {
-	sqlStmt := `
+// SetPaymentsUserCharged sets payments user charged and records ledger transaction lt, atomically.
+// Credit applied to the charge is reserved beforehand by ReserveUserCredit
+func SetPaymentsUserCharged(jUUID uuid.UUID, invoiceID string, chargeAmount int64, lt *LedgerTxn) error {
+	tx, txerr := db.Begin()
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
 		UPDATE payments
 		SET user_charged_at = NOW(),
 		user_charged_id = $2,
-		user_charged_amt = $3,
-		user_charged_credit = $4
+		user_charged_amt = $3
 		WHERE job_uuid = $1
 		`
-	if _, err := db.Exec(sqlStmt, jUUID, invoiceID, chargeAmount, credit); err != nil {
-		message := "error updating payments user charged"
+		if _, err := tx.Exec(sqlStmt, jUUID, invoiceID, chargeAmount); err != nil {
+			return "error updating payments user charged", err
+		}
+
+		if err := insertLedgerTxn(tx, lt); err != nil {
+			return "error inserting ledger txn", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			log.Sugar.Errorw(message,
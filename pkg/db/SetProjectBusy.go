@@ -0,0 +1,114 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// ProjectOp is a change to a project which has to clean up other services before it's recorded
+type ProjectOp string
+
+// Project ops
+const (
+	ProjectDeleting ProjectOp = "deleting"
+	ProjectRenaming ProjectOp = "renaming"
+)
+
+// ErrProjectBusy is returned when a project is being deleted or renamed
+var ErrProjectBusy = errors.New("project is being deleted or renamed")
+
+// SetProjectBusy marks user uUUID's project as undergoing op, so no new jobs can be posted to it
+// while its data and images are cleaned up. Marking it again with the same op is a no-op, so a
+// failed op can be retried. Returns sql.ErrNoRows if the user has no such project,
+// ErrProjectActive if any of its jobs haven't ended and ErrProjectBusy if it's undergoing
+// another op
+func SetProjectBusy(r *http.Request, uUUID uuid.UUID, project string, op ProjectOp) error {
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		pUUID := uuid.UUID{}
+		busy := sql.NullString{}
+		sqlStmt := `
+	SELECT uuid, busy
+	FROM projects
+	WHERE (name, user_uuid) = ($1, $2) AND
+		deleted_at IS NULL AND
+		org_uuid IS NULL
+	FOR UPDATE
+	`
+		if err := tx.QueryRow(sqlStmt, project, uUUID).Scan(&pUUID, &busy); err != nil {
+			return "error querying for project", err
+		} else if busy.Valid && ProjectOp(busy.String) != op {
+			return "project is busy", ErrProjectBusy
+		}
+
+		var n int64
+		sqlStmt = `
+	SELECT COUNT(*)
+	FROM jobs
+	WHERE project_uuid = $1 AND
+		state NOT IN ('finished', 'canceled', 'failed')
+	`
+		if err := tx.QueryRow(sqlStmt, pUUID).Scan(&n); err != nil {
+			return "error querying for project active jobs", err
+		} else if n > 0 {
+			return "project has active jobs", ErrProjectActive
+		}
+
+		sqlStmt = `
+	UPDATE projects
+	SET busy = $2
+	WHERE uuid = $1
+	`
+		if _, err := tx.Exec(sqlStmt, pUUID, op); err != nil {
+			return "error updating project busy", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		if err == sql.ErrNoRows || err == ErrProjectActive || err == ErrProjectBusy {
+			return err
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"uID", uUUID,
+				"project", project,
+			)
+		}
+		return err
+	}
+	return nil
+}
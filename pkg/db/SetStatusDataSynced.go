@@ -9,15 +9,35 @@ import (
 )
 
 // SetStatusDataSynced sets job jUUID status in database to data_synced=NOW()
+// and advances the job's state accordingly
 func SetStatusDataSynced(r *http.Request, jUUID uuid.UUID) *app.Error {
-	sqlStmt := `
-	UPDATE statuses
-	SET data_synced = NOW()
-	WHERE job_uuid = $1 AND
-		data_synced IS NULL
-	`
-	if _, err := db.Exec(sqlStmt, jUUID); err != nil {
-		message := "error updating job status to data synced"
+	ctx := r.Context()
+	tx, txerr := db.BeginTx(ctx, nil)
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		sqlStmt := `
+		UPDATE statuses
+		SET data_synced = NOW()
+		WHERE job_uuid = $1 AND
+			data_synced IS NULL
+		`
+		if _, err := tx.Exec(sqlStmt, jUUID); err != nil {
+			return "error updating job status to data synced", err
+		}
+
+		if err := advanceJobState(tx, jUUID); err != nil {
+			return "error advancing job state", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			log.Sugar.Errorw(message,
@@ -38,6 +58,11 @@ func SetStatusDataSynced(r *http.Request, jUUID uuid.UUID) *app.Error {
 				"jID", jUUID,
 			)
 		}
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
 		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"}
 	}
 
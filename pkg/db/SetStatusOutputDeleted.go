@@ -0,0 +1,39 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// SetStatusOutputDeleted sets job jUUID status in database to output_deleted=NOW()
+func SetStatusOutputDeleted(jUUID uuid.UUID) error {
+	sqlStmt := `
+	UPDATE statuses
+	SET output_deleted = NOW()
+	WHERE job_uuid = $1 AND
+		output_deleted IS NULL
+	`
+	if _, err := db.Exec(sqlStmt, jUUID); err != nil {
+		message := "error updating job status to output deleted"
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// TakeMinerCollateral takes up to amount (cents) of miner mUUID's held collateral, oldest first,
+// towards job jUUID's failure penalty and returns how much it took. The taken amount is recorded
+// on the job's payments row and booked from collateral to penalty in the ledger in the same
+// transaction, so calling it again returns the original amount
+func TakeMinerCollateral(jUUID, mUUID uuid.UUID, amount int64) (int64, error) {
+	var taken int64
+	tx, txerr := db.Begin()
+	if message, err := func() (string, error) {
+		if txerr != nil {
+			return errBeginTx, txerr
+		}
+
+		var done bool
+		sqlStmt := `
+		SELECT miner_collateral_taken_at IS NOT NULL, COALESCE(miner_collateral_taken, 0)
+		FROM payments
+		WHERE job_uuid = $1
+		FOR UPDATE
+		`
+		if err := tx.QueryRow(sqlStmt, jUUID).Scan(&done, &taken); err != nil {
+			return "error querying for payments collateral taken", err
+		}
+		if done {
+			return "", tx.Commit()
+		}
+
+		sqlStmt = `
+		SELECT job_uuid
+		FROM miner_collateral
+		WHERE miner_uuid = $1 AND
+			remaining > 0
+		FOR UPDATE
+		`
+		if _, err := tx.Exec(sqlStmt, mUUID); err != nil {
+			return "error locking miner collateral", err
+		}
+
+		sqlStmt = `
+		WITH held AS (
+			SELECT job_uuid, remaining,
+				SUM(remaining) OVER (ORDER BY held_at, job_uuid) - remaining AS before
+			FROM miner_collateral
+			WHERE miner_uuid = $1 AND
+				remaining > 0
+		), take AS (
+			SELECT job_uuid, LEAST(remaining, $2 - before) AS amount
+			FROM held
+			WHERE before < $2
+		)
+		UPDATE miner_collateral mc
+		SET remaining = mc.remaining - take.amount
+		FROM take
+		WHERE mc.job_uuid = take.job_uuid
+		RETURNING take.amount
+		`
+		rows, err := tx.Query(sqlStmt, mUUID, amount)
+		if err != nil {
+			return "error updating miner collateral", err
+		}
+		taken = 0
+		for rows.Next() {
+			var a int64
+			if err := rows.Scan(&a); err != nil {
+				_ = rows.Close()
+				return "error scanning miner collateral taken", err
+			}
+			taken += a
+		}
+		if err := rows.Err(); err != nil {
+			return "error scanning miner collateral taken", err
+		}
+
+		sqlStmt = `
+		UPDATE payments
+		SET miner_collateral_taken_at = NOW(),
+		miner_collateral_taken = $2
+		WHERE job_uuid = $1
+		`
+		if _, err := tx.Exec(sqlStmt, jUUID, taken); err != nil {
+			return "error updating payments collateral taken", err
+		}
+
+		lt := &LedgerTxn{
+			Kind: LedgerKindPenalty,
+			Job:  jUUID,
+			Entries: []LedgerEntry{
+				{Account: LedgerMinerCollateral, Owner: mUUID, Amount: taken},
+				{Account: LedgerPenalty, Owner: mUUID, Amount: -taken},
+			},
+		}
+		if err := insertLedgerTxn(tx, lt); err != nil {
+			return "error inserting ledger txn", err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errCommitTx, err
+		}
+
+		return "", nil
+	}(); err != nil {
+		if txerr == nil {
+			if err := tx.Rollback(); err != nil {
+				log.Sugar.Errorf("Error rolling tx back: %v", err)
+			}
+		}
+		pqErr, ok := err.(*pq.Error)
+		if ok {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"mID", mUUID,
+				"pq_sev", pqErr.Severity,
+				"pq_code", pqErr.Code,
+				"pq_msg", pqErr.Message,
+				"pq_detail", pqErr.Detail,
+			)
+		} else {
+			log.Sugar.Errorw(message,
+				"err", err.Error(),
+				"jID", jUUID,
+				"mID", mUUID,
+			)
+		}
+		return 0, err
+	}
+
+	return taken, nil
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/satori/go.uuid"
+)
+
+// insertPaymentTasks queues payment tasks of kinds for job jUUID in tx, so they commit with
+// whatever ended the job. Each job has at most one task of each kind, so queueing it again is a no-op
+func insertPaymentTasks(tx *sql.Tx, jUUID uuid.UUID, kinds []PaymentTaskKind) error {
+	sqlStmt := `
+	INSERT INTO payment_tasks (kind, job_uuid)
+	VALUES ($1, $2)
+	ON CONFLICT (kind, job_uuid) DO NOTHING
+	`
+	for _, kind := range kinds {
+		if _, err := tx.Exec(sqlStmt, kind, jUUID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
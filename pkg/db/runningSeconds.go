@@ -0,0 +1,22 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// HeartbeatGrace is how long after a running job's last miner heartbeat it's still billed
+const HeartbeatGrace = 2 * time.Minute
+
+// runningSecondsSQL is the number of seconds job j (joined with its statuses s) has been
+// billed for running: from when its miner had downloaded both its image and data until its
+// output was posted or it ended, cut off HeartbeatGrace after its last heartbeat. It matches
+// payments.Segments for use in aggregate queries
+var runningSecondsSQL = fmt.Sprintf(`
+	CASE WHEN s.data_downloaded IS NULL OR s.image_downloaded IS NULL THEN 0
+	ELSE GREATEST(EXTRACT(EPOCH FROM (
+		LEAST(s.output_data_posted,
+			COALESCE(j.completed_at, j.canceled_at, j.failed_at, NOW()),
+			j.last_heartbeat_at + INTERVAL '%d seconds') -
+		GREATEST(s.data_downloaded, s.image_downloaded))), 0)
+	END`, int(HeartbeatGrace.Seconds()))
@@ -0,0 +1,39 @@
+package email
+
+import (
+	"fmt"
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"github.com/wminshew/emrysserver/pkg/log"
+)
+
+// SendJobEnded sends an email to the user's address that their job has finished, failed or been canceled
+func SendJobEnded(email, jID, project, state string) error {
+	from := mail.NewEmail(fromName, fromAddress)
+	to := mail.NewEmail(email, email)
+	subject := fmt.Sprintf("emrys: job %s %s", jID, state)
+	text := fmt.Sprintf("Your job %s in project %s has %s.\n\n"+
+		"Download its output with the emrys cli, or reach out to %s with any questions.\n", jID, project, state, supportEmail)
+	html := fmt.Sprintf("<p>Your job <code>%s</code> in project <code>%s</code> has %s.</p>"+
+		"<p>Download its output with the emrys cli, or reach out to %s with any questions.</p>", jID, project, state, supportEmail)
+	m := mail.NewSingleEmail(from, subject, to, text, html)
+
+	request := sendgrid.GetRequest(sendgridSecret, sendgridPath, sendgridHost)
+	request.Method = "POST"
+	Body := mail.GetRequestBody(m)
+	request.Body = Body
+	response, err := sendgrid.API(request)
+	if err != nil {
+		return err
+	}
+
+	// TODO: remove? handle non 2xx status codes?
+	log.Sugar.Infow("job ended email sent",
+		"jID", jID,
+		"StatusCode", response.StatusCode,
+		"Body", response.Body,
+		"Headers", response.Headers,
+	)
+
+	return nil
+}
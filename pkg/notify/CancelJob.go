@@ -0,0 +1,17 @@
+package notify
+
+import (
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"net/http"
+)
+
+// CancelJob cancels job jUUID, inserting kinds payment tasks if its auction had completed, and
+// notifies its owner that it's ended. Errors are those of db.SetJobCanceled
+func CancelJob(r *http.Request, jUUID uuid.UUID, kinds ...db.PaymentTaskKind) error {
+	if err := db.SetJobCanceled(r, jUUID, kinds...); err != nil {
+		return err
+	}
+	go JobEnded(jUUID, db.JobStateCanceled)
+	return nil
+}
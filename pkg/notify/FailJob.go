@@ -0,0 +1,16 @@
+package notify
+
+import (
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/db"
+)
+
+// FailJob fails job jUUID, inserting kinds payment tasks, and notifies its owner that it's
+// ended. Errors are those of db.SetJobFailed
+func FailJob(jUUID uuid.UUID, kinds ...db.PaymentTaskKind) error {
+	if err := db.SetJobFailed(jUUID, kinds...); err != nil {
+		return err
+	}
+	go JobEnded(jUUID, db.JobStateFailed)
+	return nil
+}
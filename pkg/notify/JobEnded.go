@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/email"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"time"
+)
+
+// JobEnded notifies job jUUID's owner that it has ended in state via their registered
+// webhooks and, if they've opted in, email
+func JobEnded(jUUID uuid.UUID, state db.JobState) {
+	userEmail, notifyEmail, project, err := db.GetJobNotifyEmail(jUUID)
+	if err != nil {
+		return // already logged
+	}
+
+	if notifyEmail && userEmail != "" {
+		if err := email.SendJobEnded(userEmail, jUUID.String(), project, string(state)); err != nil {
+			log.Sugar.Errorw("error sending job ended email",
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+		}
+	}
+
+	body, err := json.Marshal(&jobEndedPayload{
+		Event:   jobEndedEvent,
+		JobID:   jUUID.String(),
+		Project: project,
+		State:   string(state),
+		Time:    time.Now(),
+	})
+	if err != nil {
+		log.Sugar.Errorw("error marshaling job ended payload",
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return
+	}
+
+	rows, err := db.GetJobWebhooks(jUUID)
+	if err != nil {
+		return // already logged
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Sugar.Errorf("Error closing rows")
+		}
+	}()
+
+	for rows.Next() {
+		var u, secret string
+		if err := rows.Scan(&u, &secret); err != nil {
+			log.Sugar.Errorw("error scanning job webhooks",
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+			return
+		}
+		go func() {
+			if err := postWebhook(u, secret, jobEndedEvent, body); err != nil {
+				log.Sugar.Errorw("error posting job ended webhook",
+					"err", err.Error(),
+					"jID", jUUID,
+					"webhook", u,
+				)
+			}
+		}()
+	}
+	if err := rows.Err(); err != nil {
+		log.Sugar.Errorw("error scanning job webhooks",
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+	}
+}
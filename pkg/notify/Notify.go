@@ -0,0 +1,21 @@
+package notify
+
+import (
+	"time"
+)
+
+const (
+	maxRetries      = 10
+	signatureHeader = "X-Emrys-Signature"
+	eventHeader     = "X-Emrys-Event"
+	jobEndedEvent   = "job.ended"
+	webhookTimeout  = 10 * time.Second
+)
+
+type jobEndedPayload struct {
+	Event   string    `json:"event"`
+	JobID   string    `json:"jobID"`
+	Project string    `json:"project"`
+	State   string    `json:"state"`
+	Time    time.Time `json:"time"`
+}
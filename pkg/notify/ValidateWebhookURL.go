@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress is returned for webhook urls whose host isn't a public internet address,
+// so webhooks can't be pointed at emrys' own services or the cloud metadata server
+var ErrNonPublicAddress = errors.New("webhook host is not a public address")
+
+// nonPublicNets are the address ranges webhooks may not be sent to, besides those net.IP
+// already classifies as loopback, link-local, multicast or unspecified
+var nonPublicNets = func() []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, cidr := range []string{
+		"0.0.0.0/8",      // "this" network
+		"10.0.0.0/8",     // private
+		"172.16.0.0/12",  // private
+		"192.168.0.0/16", // private
+		"fc00::/7",       // unique local
+		"100.64.0.0/10",  // carrier-grade NAT
+		"192.0.0.0/24",   // IETF protocol assignments
+		"198.18.0.0/15",  // benchmarking
+		"240.0.0.0/4",    // reserved
+		"64:ff9b::/96",   // NAT64
+	} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// publicIP returns whether ip is a public internet address
+func publicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateWebhookURL returns an error unless rawURL is an absolute https url whose host only
+// resolves to public addresses
+func ValidateWebhookURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("webhook url must be an absolute https url")
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolving webhook host: %v", err)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return ErrNonPublicAddress
+		}
+	}
+	return nil
+}
+
+// webhookTransport only connects to public addresses. The check runs on the address actually
+// dialed, after resolution, so a host re-pointed at an internal address after it was
+// registered (DNS rebinding) is still refused
+var webhookTransport = &http.Transport{
+	DialContext: (&net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicIP(net.ParseIP(host)) {
+				return ErrNonPublicAddress
+			}
+			return nil
+		},
+	}).DialContext,
+	TLSHandshakeTimeout:   webhookTimeout,
+	ResponseHeaderTimeout: webhookTimeout,
+	IdleConnTimeout:       90 * time.Second,
+}
+
+// nonPublicAddressErr returns whether err is a request refused by webhookTransport
+func nonPublicAddressErr(err error) bool {
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	if oe, ok := err.(*net.OpError); ok {
+		err = oe.Err
+	}
+	return err == ErrNonPublicAddress
+}
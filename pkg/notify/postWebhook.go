@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/cenkalti/backoff"
+	"github.com/pkg/errors"
+	"github.com/wminshew/emrys/pkg/check"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Sign returns the signature sent in the X-Emrys-Signature header: the hex HMAC-SHA256 of body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook posts body to url signed with secret, retrying with exponential backoff
+// on network errors, 5xx and 429 responses. Only public addresses are dialed
+func postWebhook(url, secret, event string, body []byte) error {
+	ctx := context.Background()
+	client := &http.Client{
+		Transport: webhookTransport,
+		Timeout:   webhookTimeout,
+	}
+	signature := Sign(secret, body)
+
+	operation := func() error {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(eventHeader, event)
+		req.Header.Set(signatureHeader, signature)
+		req = req.WithContext(ctx)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if nonPublicAddressErr(err) {
+				return backoff.Permanent(err)
+			}
+			return err
+		}
+		defer check.Err(resp.Body.Close)
+
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("webhook: temporary error: %s", resp.Status)
+		} else if resp.StatusCode >= 300 {
+			b, _ := ioutil.ReadAll(resp.Body)
+			return backoff.Permanent(fmt.Errorf("webhook: %s: %v", resp.Status, string(b)))
+		}
+
+		return nil
+	}
+	if err := backoff.RetryNotify(operation,
+		backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries), ctx),
+		func(err error, t time.Duration) {
+			log.Sugar.Errorw("error posting webhook, retrying",
+				"err", err.Error(),
+				"webhook", url,
+			)
+		}); err != nil {
+		return errors.Wrap(err, "posting webhook")
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"github.com/satori/go.uuid"
+	stripe "github.com/stripe/stripe-go"
+	"github.com/stripe/stripe-go/invoiceitem"
+	"github.com/stripe/stripe-go/reversal"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// Refund methods
+const (
+	// RefundInvoice credits the refund against the payer's next stripe invoice
+	RefundInvoice = "invoice"
+	// RefundCredit adds the refund to the job owner's account credit
+	RefundCredit = "credit"
+)
+
+var (
+	// ErrJobNotCharged is returned when refunding a job whose user hasn't been charged yet
+	ErrJobNotCharged = errors.New("job's user hasn't been charged yet")
+	// ErrJobNotPaid is returned when reversing the transfer of a job whose miner hasn't been paid
+	ErrJobNotPaid = errors.New("job's miner hasn't been paid by transfer")
+	// ErrAdjustmentTooLarge is returned when a refund or reversal exceeds what's left of the charge or transfer
+	ErrAdjustmentTooLarge = errors.New("amount exceeds what's left to refund or reverse")
+	// ErrOrgCreditRefund is returned when refunding an org job as account credit
+	ErrOrgCreditRefund = errors.New("org jobs can't be refunded as account credit")
+)
+
+// RefundUser refunds amount (cents, 0 for everything not yet refunded) of job jUUID's charge to
+// its payer by method, on behalf of admin adminUUID for reason. The refund is recorded as pending
+// adjustment adjUUID before stripe is called, so retrying with the same adjUUID after an error
+// finishes it rather than refunding again
+func RefundUser(r *http.Request, stripeInvoiceItemC *invoiceitem.Client, adjUUID, jUUID uuid.UUID, amount int64,
+	method, reason string, adminUUID uuid.UUID) (*db.PaymentAdjustment, error) {
+	aUUID, oUUID, stripeCustomerID, stripeSubscriptionID, err := getJobBilling(r, jUUID)
+	if err != nil {
+		return nil, err // already logged
+	}
+	personal := uuid.Equal(oUUID, uuid.Nil)
+
+	adj := &db.PaymentAdjustment{
+		UUID:    adjUUID,
+		Kind:    db.PaymentAdjustmentRefund,
+		Job:     jUUID,
+		Account: aUUID,
+		Amount:  amount,
+		Method:  method,
+		Reason:  reason,
+		Admin:   adminUUID,
+	}
+	switch method {
+	case RefundCredit:
+		if !personal {
+			return nil, ErrOrgCreditRefund
+		}
+	case RefundInvoice:
+		if !personal {
+			adj.Account = oUUID
+		}
+	default:
+		return nil, fmt.Errorf("unknown refund method %s", method)
+	}
+	if finalized, err := db.ReservePaymentAdjustment(r, adj, func(ri *db.JobRefundInfo) error {
+		if !ri.UserCharged {
+			return ErrJobNotCharged
+		}
+		left := ri.Charged - ri.Refunded
+		if adj.Amount == 0 {
+			adj.Amount = left
+		}
+		if adj.Amount <= 0 || adj.Amount > left {
+			return ErrAdjustmentTooLarge
+		}
+		return nil
+	}); err != nil {
+		return nil, err // already logged
+	} else if finalized {
+		return adj, nil
+	}
+
+	lt := &db.LedgerTxn{
+		Kind: db.LedgerKindRefund,
+		Job:  jUUID,
+	}
+	var creditDelta int64
+	switch adj.Method {
+	case RefundCredit:
+		creditDelta = adj.Amount
+		lt.Entries = []db.LedgerEntry{
+			{Account: db.LedgerRefunds, Amount: adj.Amount},
+			{Account: db.LedgerUserCredit, Owner: adj.Account, Amount: -adj.Amount},
+		}
+	case RefundInvoice:
+		params := &stripe.InvoiceItemParams{
+			Customer:     stripe.String(stripeCustomerID),
+			Subscription: stripe.String(stripeSubscriptionID),
+			Amount:       stripe.Int64(-adj.Amount),
+			Currency:     stripe.String(string(stripe.CurrencyUSD)),
+			Description:  stripe.String(fmt.Sprintf("Refund for job %s", jUUID.String())),
+		}
+		params.SetIdempotencyKey(fmt.Sprintf("refund-%s", adj.UUID))
+		ii, err := stripeInvoiceItemC.New(params)
+		if err != nil {
+			log.Sugar.Errorw("error creating refund invoice item",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"adjID", adj.UUID,
+				"jID", jUUID,
+			)
+			releaseAdjustment(r, adj, err)
+			return nil, err
+		}
+		adj.StripeID = ii.ID
+		lt.StripeID = ii.ID
+		lt.Entries = []db.LedgerEntry{
+			{Account: db.LedgerRefunds, Amount: adj.Amount},
+			{Account: db.LedgerUserReceivable, Owner: adj.Account, Amount: -adj.Amount},
+		}
+	}
+
+	if err := db.FinalizePaymentAdjustment(r, adj, creditDelta, lt); err != nil {
+		return nil, err // already logged
+	}
+	return adj, nil
+}
+
+// ReverseMinerTransfer claws amount (cents, 0 for everything not yet reversed) of job jUUID's
+// payout back from its miner, on behalf of admin adminUUID for reason. Like RefundUser, retrying
+// with the same adjUUID after an error finishes the reversal rather than reversing again
+func ReverseMinerTransfer(r *http.Request, stripeReversalC *reversal.Client, adjUUID, jUUID uuid.UUID, amount int64,
+	reason string, adminUUID uuid.UUID) (*db.PaymentAdjustment, error) {
+	mUUID, err := db.GetJobWinner(jUUID)
+	if err != nil {
+		return nil, err // already logged
+	}
+
+	adj := &db.PaymentAdjustment{
+		UUID:    adjUUID,
+		Kind:    db.PaymentAdjustmentTransferReversal,
+		Job:     jUUID,
+		Account: mUUID,
+		Amount:  amount,
+		Method:  "transfer",
+		Reason:  reason,
+		Admin:   adminUUID,
+	}
+	if finalized, err := db.ReservePaymentAdjustment(r, adj, func(ri *db.JobRefundInfo) error {
+		if !ri.MinerPaid || ri.MinerTransferID == "" {
+			return ErrJobNotPaid
+		}
+		left := ri.Transferred - ri.Reversed
+		if adj.Amount == 0 {
+			adj.Amount = left
+		}
+		if adj.Amount <= 0 || adj.Amount > left {
+			return ErrAdjustmentTooLarge
+		}
+		return nil
+	}); err != nil {
+		return nil, err // already logged
+	} else if finalized {
+		return adj, nil
+	}
+
+	ri, err := db.GetJobRefundInfo(jUUID)
+	if err != nil {
+		return nil, err // already logged
+	}
+	params := &stripe.ReversalParams{
+		Transfer:    stripe.String(ri.MinerTransferID),
+		Amount:      stripe.Int64(adj.Amount),
+		Description: stripe.String(fmt.Sprintf("Reversal for job %s", jUUID.String())),
+	}
+	params.SetIdempotencyKey(fmt.Sprintf("reversal-%s", adj.UUID))
+	rev, err := stripeReversalC.New(params)
+	if err != nil {
+		log.Sugar.Errorw("error creating miner transfer reversal",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"adjID", adj.UUID,
+			"jID", jUUID,
+		)
+		releaseAdjustment(r, adj, err)
+		return nil, err
+	}
+	adj.StripeID = rev.ID
+
+	lt := &db.LedgerTxn{
+		Kind:     db.LedgerKindTransferReversal,
+		Job:      jUUID,
+		StripeID: rev.ID,
+		Entries: []db.LedgerEntry{
+			{Account: db.LedgerStripeBalance, Amount: rev.Amount},
+			{Account: db.LedgerPenalty, Owner: mUUID, Amount: -rev.Amount},
+		},
+	}
+	if err := db.FinalizePaymentAdjustment(r, adj, 0, lt); err != nil {
+		return nil, err // already logged
+	}
+	return adj, nil
+}
+
+// releaseAdjustment deletes pending adjustment adj if stripe definitely didn't make it, so its
+// amount can be adjusted again. Otherwise it stays pending, to be finished by a retry
+func releaseAdjustment(r *http.Request, adj *db.PaymentAdjustment, err error) {
+	if stripeRejected(err) {
+		_ = db.DeletePaymentAdjustment(r, adj.UUID) // already logged
+	}
+}
+
+// stripeRejected returns whether err from a stripe call means the request definitely had no
+// effect, as opposed to a network or server error after which it may have gone through
+func stripeRejected(err error) bool {
+	stripeErr, ok := err.(*stripe.Error)
+	return ok && stripeErr.HTTPStatusCode >= http.StatusBadRequest && stripeErr.HTTPStatusCode < http.StatusInternalServerError &&
+		stripeErr.HTTPStatusCode != http.StatusConflict && stripeErr.HTTPStatusCode != http.StatusTooManyRequests
+}
+
+// AdjustCredit adds amount (cents, negative to deduct) to account aUUID's credit, optionally
+// tied to job jUUID (uuid.Nil if none), on behalf of admin adminUUID for reason
+func AdjustCredit(r *http.Request, aUUID, jUUID uuid.UUID, amount int64, reason string,
+	adminUUID uuid.UUID) (*db.PaymentAdjustment, error) {
+	adj := &db.PaymentAdjustment{
+		UUID:    uuid.NewV4(),
+		Kind:    db.PaymentAdjustmentCredit,
+		Job:     jUUID,
+		Account: aUUID,
+		Amount:  amount,
+		Method:  RefundCredit,
+		Reason:  reason,
+		Admin:   adminUUID,
+	}
+	lt := &db.LedgerTxn{
+		Kind: db.LedgerKindAdjustment,
+		Job:  jUUID,
+		Entries: []db.LedgerEntry{
+			{Account: db.LedgerPromoExpense, Amount: amount},
+			{Account: db.LedgerUserCredit, Owner: aUUID, Amount: -amount},
+		},
+	}
+	if err := db.InsertPaymentAdjustment(r, adj, amount, lt); err != nil {
+		return nil, err // already logged
+	}
+	return adj, nil
+}
@@ -1,51 +1,74 @@
 package payments
 
 import (
-	"context"
 	"fmt"
-	"github.com/cenkalti/backoff"
 	"github.com/satori/go.uuid"
 	stripe "github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/charge"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
-	"time"
 )
 
 const baseMinerPenalty = 50
 
-// ChargeMiner pays the miner for job jUUID
-func ChargeMiner(stripeChargeC *charge.Client, jUUID uuid.UUID) {
+// ChargeMiner charges the miner the failure penalty for job jUUID, taking it from their held
+// collateral first and charging their stripe account for the rest. It makes a single attempt and
+// does nothing if the miner has already been charged
+func ChargeMiner(stripeChargeC *charge.Client, jUUID uuid.UUID) error {
+	_, _, minerCharged, err := db.GetPaymentsStatus(jUUID)
+	if err != nil {
+		return err // already logged
+	}
+	if minerCharged {
+		return nil
+	}
+
 	aUUID, err := db.GetJobWinner(jUUID)
 	if err != nil {
 		log.Sugar.Errorw("error getting job winner",
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
 
-	stripeAccountID, err := db.GetAccountStripeAccountID(aUUID)
+	jobAmount, err := getJobAmount(jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error getting stripe account ID",
+		log.Sugar.Errorw("error getting job amount",
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
+	penalty := jobAmount + baseMinerPenalty
 
-	jobAmount, err := getJobAmount(jUUID)
+	// the collateral taken is booked to the ledger along with it
+	taken, err := db.TakeMinerCollateral(jUUID, aUUID, penalty)
 	if err != nil {
-		log.Sugar.Errorw("error getting job amount",
+		return err // already logged
+	}
+	if taken == penalty {
+		if err := db.SetPaymentsMinerCharged(jUUID, "", 0, nil); err != nil {
+			log.Sugar.Errorw("error setting payments miner charged",
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+			return err
+		}
+		return nil
+	}
+
+	stripeAccountID, err := db.GetAccountStripeAccountID(aUUID)
+	if err != nil {
+		log.Sugar.Errorw("error getting stripe account ID",
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
-	jobAmount += baseMinerPenalty
 
 	params := &stripe.ChargeParams{
-		Amount:      stripe.Int64(jobAmount),
+		Amount:      stripe.Int64(penalty - taken),
 		Currency:    stripe.String(string(stripe.CurrencyUSD)),
 		Description: stripe.String(fmt.Sprintf("Failure penalty for job %s", jUUID.String())),
 	}
@@ -54,39 +77,39 @@ func ChargeMiner(stripeChargeC *charge.Client, jUUID uuid.UUID) {
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
-	}
-	params.SetIdempotencyKey(uuid.NewV4().String())
-
-	ctx := context.Background()
-	ch := &stripe.Charge{}
-	operation := func() error {
-		var err error
-		ch, err = stripeChargeC.New(params)
 		return err
 	}
-	expBackOff := backoff.NewExponentialBackOff()
-	expBackOff.MaxElapsedTime = maxBackoffElapsedTime
-	if err := backoff.RetryNotify(operation,
-		backoff.WithContext(expBackOff, ctx),
-		func(err error, t time.Duration) {
-			log.Sugar.Errorw("error creating miner charge, retrying",
-				"err", err.Error(),
-				"jID", jUUID,
-			)
-		}); err != nil {
-		log.Sugar.Errorw("error creating miner charge--aborting",
+	key, err := db.GetPaymentIdempotencyKey(db.PaymentTaskChargeMiner, jUUID)
+	if err != nil {
+		return err // already logged
+	}
+	params.SetIdempotencyKey(key)
+
+	ch, err := stripeChargeC.New(params)
+	if err != nil {
+		log.Sugar.Errorw("error creating miner charge",
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
 
-	if err := db.SetPaymentsMinerCharged(jUUID, ch.ID, ch.Amount); err != nil {
+	lt := &db.LedgerTxn{
+		Kind:     db.LedgerKindPenalty,
+		Job:      jUUID,
+		StripeID: ch.ID,
+		Entries: []db.LedgerEntry{
+			{Account: db.LedgerStripeBalance, Amount: ch.Amount},
+			{Account: db.LedgerPenalty, Owner: aUUID, Amount: -ch.Amount},
+		},
+	}
+	if err := db.SetPaymentsMinerCharged(jUUID, ch.ID, ch.Amount, lt); err != nil {
 		log.Sugar.Errorw("error setting payments miner charged",
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
+
+	return nil
 }
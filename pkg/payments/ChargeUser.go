@@ -1,159 +1,126 @@
 package payments
 
 import (
-	"context"
 	"fmt"
-	"github.com/cenkalti/backoff"
 	"github.com/satori/go.uuid"
 	stripe "github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/invoiceitem"
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
 	"net/http"
-	"time"
 )
 
-const (
-	maxBackoffElapsedTime = 72 * time.Hour
-)
-
-// ChargeUser charges the user for job jUUID
-func ChargeUser(r *http.Request, stripeInvoiceItemC *invoiceitem.Client, jUUID uuid.UUID) {
-	aUUID, err := db.GetJobOwner(r, jUUID)
+// ChargeUser charges the user for job jUUID, or its organization if the job is in an org project.
+// It makes a single attempt and does nothing if the user has already been charged
+func ChargeUser(r *http.Request, stripeInvoiceItemC *invoiceitem.Client, jUUID uuid.UUID) error {
+	userCharged, _, _, err := db.GetPaymentsStatus(jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error getting job owner",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-			"jID", jUUID,
-		)
-		return
+		return err // already logged
+	}
+	if userCharged {
+		return nil
 	}
 
-	stripeCustomerID, err := db.GetAccountStripeCustomerID(r, aUUID)
+	aUUID, oUUID, stripeCustomerID, stripeSubscriptionID, err := getJobBilling(r, jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error getting stripe customer ID",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-			"jID", jUUID,
-		)
-		return
+		return err // already logged
 	}
+	// jobs in org projects are billed to the org and don't draw on the creator's credit
+	personal := uuid.Equal(oUUID, uuid.Nil)
 
-	stripeSubscriptionID, err := db.GetAccountStripeSubscriptionID(r, aUUID)
+	jf, err := getJobFees(jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error getting stripe subscription ID",
+		log.Sugar.Errorw("error getting job fees",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
+	jobAmount := jf.Amount
 
-	jobAmount, err := getJobAmount(jUUID)
-	if err != nil {
-		log.Sugar.Errorw("error getting job amount",
-			"method", r.Method,
-			"url", r.URL,
-			"err", err.Error(),
-			"jID", jUUID,
-		)
-		return
+	var credit int64
+	if personal {
+		credit, err = db.ReserveUserCredit(jUUID, aUUID, jobAmount)
+		if err != nil {
+			return err // already logged
+		}
 	}
+	invoiceAmount := jobAmount - credit
 
-	credit, err := db.GetAccountCredit(aUUID)
+	mUUID, err := db.GetJobWinner(jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error getting account credit",
+		log.Sugar.Errorw("error getting job winner",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
+	}
+	payer := aUUID
+	if !personal {
+		payer = oUUID
+	}
+	lt := &db.LedgerTxn{
+		Kind: db.LedgerKindJobCharge,
+		Job:  jUUID,
+		Entries: []db.LedgerEntry{
+			{Account: db.LedgerUserReceivable, Owner: payer, Amount: invoiceAmount},
+			{Account: db.LedgerUserCredit, Owner: aUUID, Amount: credit},
+			{Account: db.LedgerMinerPayable, Owner: mUUID, Amount: -jf.MinerAmount},
+			{Account: db.LedgerPlatformFee, Amount: -jf.Fee},
+		},
 	}
 
-	if credit >= jobAmount {
-		credit -= jobAmount
-		if err := db.SetAccountCredit(aUUID, credit); err != nil {
-			log.Sugar.Errorw("error setting account credit",
-				"method", r.Method,
-				"url", r.URL,
-				"err", err.Error(),
-				"jID", jUUID,
-			)
-			return
-		}
-
-		if err := db.SetPaymentsUserCharged(jUUID, "", 0, jobAmount); err != nil {
+	if invoiceAmount == 0 {
+		if err := db.SetPaymentsUserCharged(jUUID, "", 0, lt); err != nil {
 			log.Sugar.Errorw("error setting payments user charged",
 				"method", r.Method,
 				"url", r.URL,
 				"err", err.Error(),
 				"jID", jUUID,
 			)
-			return
-		}
-
-		return
-	} else if credit > 0 {
-		jobAmount -= credit
-		if err := db.SetAccountCredit(aUUID, 0); err != nil {
-			log.Sugar.Errorw("error setting account credit",
-				"method", r.Method,
-				"url", r.URL,
-				"err", err.Error(),
-				"jID", jUUID,
-			)
-			return
+			return err
 		}
+		return nil
 	}
 
 	params := &stripe.InvoiceItemParams{
 		Customer:     stripe.String(stripeCustomerID),
 		Subscription: stripe.String(stripeSubscriptionID),
-		Amount:       stripe.Int64(jobAmount),
+		Amount:       stripe.Int64(invoiceAmount),
 		Currency:     stripe.String(string(stripe.CurrencyUSD)),
 		Description:  stripe.String(fmt.Sprintf("Payment for job %s", jUUID.String())),
 	}
-	params.SetIdempotencyKey(uuid.NewV4().String())
-
-	ctx := context.Background()
-	ii := &stripe.InvoiceItem{}
-	operation := func() error {
-		var err error
-		ii, err = stripeInvoiceItemC.New(params)
-		return err
+	key, err := db.GetPaymentIdempotencyKey(db.PaymentTaskChargeUser, jUUID)
+	if err != nil {
+		return err // already logged
 	}
-	expBackOff := backoff.NewExponentialBackOff()
-	expBackOff.MaxElapsedTime = maxBackoffElapsedTime
-	if err := backoff.RetryNotify(operation,
-		backoff.WithContext(expBackOff, ctx),
-		func(err error, t time.Duration) {
-			log.Sugar.Errorw("error creating user invoice, retrying",
-				"method", r.Method,
-				"url", r.URL,
-				"err", err.Error(),
-				"jID", jUUID,
-			)
-		}); err != nil {
-		log.Sugar.Errorw("error creating user invoice--aborting",
+	params.SetIdempotencyKey(key)
+
+	ii, err := stripeInvoiceItemC.New(params)
+	if err != nil {
+		log.Sugar.Errorw("error creating user invoice",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
 
-	if err := db.SetPaymentsUserCharged(jUUID, ii.ID, ii.Amount, credit); err != nil {
+	lt.StripeID = ii.ID
+	if err := db.SetPaymentsUserCharged(jUUID, ii.ID, ii.Amount, lt); err != nil {
 		log.Sugar.Errorw("error setting payments user charged",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
+
+	return nil
 }
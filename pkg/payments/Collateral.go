@@ -0,0 +1,109 @@
+package payments
+
+import (
+	"fmt"
+	"github.com/satori/go.uuid"
+	stripe "github.com/stripe/stripe-go"
+	"github.com/stripe/stripe-go/transfer"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"time"
+)
+
+const (
+	// CollateralPercent is the share of each miner payout held back as collateral
+	CollateralPercent = 10
+	// CollateralCap is the most collateral (cents) held back from a miner at once
+	CollateralCap = 10000
+	// CollateralHoldPeriod is how long collateral is held back before it's paid to the miner
+	CollateralHoldPeriod = 30 * 24 * time.Hour
+)
+
+// collateralHold returns how much (cents) of minerAmount to hold back from miner mUUID's payout,
+// keeping their held collateral within CollateralCap
+func collateralHold(mUUID uuid.UUID, minerAmount int64) (int64, error) {
+	held, err := db.GetMinerCollateralHeld(mUUID)
+	if err != nil {
+		return 0, err // already logged
+	}
+	return CollateralHold(minerAmount, held), nil
+}
+
+// CollateralHold returns how much (cents) of minerAmount is held back from the payout of a miner
+// already holding held cents of collateral
+func CollateralHold(minerAmount, held int64) int64 {
+	hold := minerAmount * CollateralPercent / 100
+	if hold > CollateralCap-held {
+		hold = CollateralCap - held
+	}
+	if hold < 0 {
+		hold = 0
+	}
+	return hold
+}
+
+// ReleaseCollateral pays the miner whatever collateral held back from job jUUID's payout wasn't
+// taken by penalties. It makes a single attempt and does nothing if it's already been paid
+func ReleaseCollateral(r *http.Request, stripeTransferC *transfer.Client, jUUID uuid.UUID) error {
+	mUUID, amount, transferID, err := db.ReserveCollateralRelease(jUUID)
+	if err != nil {
+		return err // already logged
+	}
+	if transferID != "" {
+		return nil
+	}
+	if amount == 0 {
+		// penalties took all of it
+		return nil
+	}
+
+	stripeAccountID, err := db.GetAccountStripeAccountID(mUUID)
+	if err != nil {
+		log.Sugar.Errorw("error getting stripe account ID",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return err
+	}
+
+	params := &stripe.TransferParams{
+		Destination:   stripe.String(stripeAccountID),
+		Amount:        stripe.Int64(amount),
+		Currency:      stripe.String(string(stripe.CurrencyUSD)),
+		TransferGroup: stripe.String(fmt.Sprintf("Collateral release for job %s", jUUID.String())),
+	}
+	key, err := db.GetPaymentIdempotencyKey(db.PaymentTaskReleaseCollateral, jUUID)
+	if err != nil {
+		return err // already logged
+	}
+	params.SetIdempotencyKey(key)
+
+	t, err := stripeTransferC.New(params)
+	if err != nil {
+		log.Sugar.Errorw("error creating collateral release transfer",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return err
+	}
+
+	lt := &db.LedgerTxn{
+		Kind:     db.LedgerKindCollateralRelease,
+		Job:      jUUID,
+		StripeID: t.ID,
+		Entries: []db.LedgerEntry{
+			{Account: db.LedgerMinerCollateral, Owner: mUUID, Amount: t.Amount},
+			{Account: db.LedgerStripeBalance, Amount: -t.Amount},
+		},
+	}
+	if err := db.SetCollateralReleased(jUUID, t.ID, lt); err != nil {
+		return err // already logged
+	}
+
+	return nil
+}
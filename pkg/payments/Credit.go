@@ -0,0 +1,155 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"github.com/satori/go.uuid"
+	stripe "github.com/stripe/stripe-go"
+	"github.com/stripe/stripe-go/charge"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// CreditPack is a pack of prepaid credit users can buy. Price and Credit are in cents; larger
+// packs come with bonus credit
+type CreditPack struct {
+	ID     string `json:"id"`
+	Price  int64  `json:"price"`
+	Credit int64  `json:"credit"`
+}
+
+// CreditPacks are the packs of credit for sale
+var CreditPacks = []CreditPack{
+	{ID: "10", Price: 1000, Credit: 1000},
+	{ID: "25", Price: 2500, Credit: 2500},
+	{ID: "50", Price: 5000, Credit: 5250},
+	{ID: "100", Price: 10000, Credit: 11000},
+}
+
+var (
+	// ErrUnknownCreditPack is returned when buying a credit pack that isn't for sale
+	ErrUnknownCreditPack = errors.New("unknown credit pack")
+	// ErrNoPaymentMethod is returned when buying credit for an account without a stripe customer
+	ErrNoPaymentMethod = errors.New("account has no payment method")
+)
+
+// GetCreditPack returns the credit pack id, if it's for sale
+func GetCreditPack(id string) (CreditPack, bool) {
+	for _, p := range CreditPacks {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return CreditPack{}, false
+}
+
+// PurchaseCredit charges account aUUID's stripe customer for credit pack packID and adds its
+// credit to the account. triggerJob is the job whose charge set off an auto-recharge, or uuid.Nil
+// if the user is buying it. The purchase is recorded as pending purchase cpUUID before the card is
+// charged, with check (if not nil) run against the account's credit under lock, so retrying with
+// the same cpUUID after an error finishes the purchase rather than charging again
+func PurchaseCredit(r *http.Request, stripeChargeC *charge.Client, cpUUID, aUUID uuid.UUID, packID string,
+	triggerJob uuid.UUID, check func(credit int64) error) (*db.CreditPurchase, error) {
+	pack, ok := GetCreditPack(packID)
+	if !ok {
+		return nil, ErrUnknownCreditPack
+	}
+
+	stripeCustomerID, err := db.GetAccountStripeCustomerID(r, aUUID)
+	if err != nil {
+		return nil, err // already logged
+	} else if stripeCustomerID == "" {
+		return nil, ErrNoPaymentMethod
+	}
+
+	cp := &db.CreditPurchase{
+		UUID:       cpUUID,
+		Account:    aUUID,
+		Pack:       pack.ID,
+		Price:      pack.Price,
+		Credit:     pack.Credit,
+		TriggerJob: triggerJob,
+	}
+	if finalized, err := db.ReserveCreditPurchase(r, cp, check); err != nil {
+		return nil, err // already logged
+	} else if finalized {
+		return cp, nil
+	}
+
+	params := &stripe.ChargeParams{
+		Customer:    stripe.String(stripeCustomerID),
+		Amount:      stripe.Int64(cp.Price),
+		Currency:    stripe.String(string(stripe.CurrencyUSD)),
+		Description: stripe.String(fmt.Sprintf("Emrys credit pack $%s", cp.Pack)),
+	}
+	params.SetIdempotencyKey(fmt.Sprintf("credit-purchase-%s", cp.UUID))
+	ch, err := stripeChargeC.New(params)
+	if err != nil {
+		log.Sugar.Errorw("error charging for credit pack",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"cpID", cp.UUID,
+			"aID", aUUID,
+			"pack", cp.Pack,
+		)
+		if stripeRejected(err) {
+			_ = db.DeleteCreditPurchase(r, cp.UUID) // already logged
+		}
+		return nil, err
+	}
+	cp.Price = ch.Amount
+	cp.StripeID = ch.ID
+
+	lt := &db.LedgerTxn{
+		Kind:     db.LedgerKindCreditPurchase,
+		Job:      triggerJob,
+		StripeID: ch.ID,
+		Entries: []db.LedgerEntry{
+			{Account: db.LedgerStripeBalance, Amount: ch.Amount},
+			{Account: db.LedgerPromoExpense, Amount: cp.Credit - ch.Amount},
+			{Account: db.LedgerUserCredit, Owner: aUUID, Amount: -cp.Credit},
+		},
+	}
+	if err := db.FinalizeCreditPurchase(r, cp, lt); err != nil {
+		return nil, err // already logged
+	}
+	return cp, nil
+}
+
+// errCreditAboveThreshold is returned by RechargeCredit's check when the account no longer needs recharging
+var errCreditAboveThreshold = errors.New("credit is above auto-recharge threshold")
+
+// RechargeCredit buys the auto-recharge credit pack for job jUUID's owner if the job's charge
+// left their credit below their threshold. It makes a single attempt and does nothing if
+// auto-recharge is off or the recharge has already been recorded. The purchase id is derived from
+// the job, and recharges pending for the account count as credit, so neither a retry nor another
+// job's concurrent recharge buys the pack again
+func RechargeCredit(r *http.Request, stripeChargeC *charge.Client, jUUID uuid.UUID) error {
+	aUUID, err := db.GetJobOwner(r, jUUID)
+	if err != nil {
+		return err // already logged
+	}
+
+	packID, threshold, err := db.GetAutoRecharge(r, aUUID)
+	if err != nil {
+		return err // already logged
+	}
+	if packID == "" {
+		return nil
+	}
+
+	cpUUID := uuid.NewV5(jUUID, string(db.PaymentTaskRechargeCredit))
+	if _, err := PurchaseCredit(r, stripeChargeC, cpUUID, aUUID, packID, jUUID, func(credit int64) error {
+		if credit >= threshold {
+			return errCreditAboveThreshold
+		}
+		return nil
+	}); err == errCreditAboveThreshold || err == db.ErrCreditPurchaseExists {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
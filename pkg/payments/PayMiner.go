@@ -1,9 +1,7 @@
 package payments
 
 import (
-	"context"
 	"fmt"
-	"github.com/cenkalti/backoff"
 	"github.com/satori/go.uuid"
 	stripe "github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/transfer"
@@ -13,8 +11,18 @@ import (
 	"time"
 )
 
-// PayMiner pays the miner for job jUUID
-func PayMiner(r *http.Request, stripeTransferC *transfer.Client, jUUID uuid.UUID) {
+// PayMiner pays the miner for job jUUID, less the platform fee and the collateral held back
+// against failure penalties. It makes a single attempt and does nothing if the miner has
+// already been paid
+func PayMiner(r *http.Request, stripeTransferC *transfer.Client, jUUID uuid.UUID) error {
+	_, minerPaid, _, err := db.GetPaymentsStatus(jUUID)
+	if err != nil {
+		return err // already logged
+	}
+	if minerPaid {
+		return nil
+	}
+
 	aUUID, err := db.GetJobWinner(jUUID)
 	if err != nil {
 		log.Sugar.Errorw("error getting job winner",
@@ -23,7 +31,7 @@ func PayMiner(r *http.Request, stripeTransferC *transfer.Client, jUUID uuid.UUID
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
 
 	stripeAccountID, err := db.GetAccountStripeAccountID(aUUID)
@@ -34,63 +42,88 @@ func PayMiner(r *http.Request, stripeTransferC *transfer.Client, jUUID uuid.UUID
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
 
-	jobAmount, err := getJobAmount(jUUID)
+	jf, err := getJobFees(jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error getting job amount",
+		log.Sugar.Errorw("error getting job fees",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
-
-	params := &stripe.TransferParams{
-		Destination:   stripe.String(stripeAccountID),
-		Amount:        stripe.Int64(jobAmount),
-		Currency:      stripe.String(string(stripe.CurrencyUSD)),
-		TransferGroup: stripe.String(fmt.Sprintf("Payout for job %s", jUUID.String())),
+	hold, err := collateralHold(aUUID, jf.MinerAmount)
+	if err != nil {
+		return err // already logged
 	}
-	params.SetIdempotencyKey(uuid.NewV4().String())
-
-	ctx := context.Background()
-	t := &stripe.Transfer{}
-	operation := func() error {
-		var err error
-		t, err = stripeTransferC.New(params)
-		return err
+	if hold, err = db.SetPaymentsCollateralHold(jUUID, hold); err != nil {
+		return err // already logged
+	}
+	ch := &db.CollateralHold{
+		Miner:     aUUID,
+		Amount:    hold,
+		ReleaseAt: time.Now().Add(CollateralHoldPeriod),
 	}
-	expBackOff := backoff.NewExponentialBackOff()
-	expBackOff.MaxElapsedTime = maxBackoffElapsedTime
-	if err := backoff.RetryNotify(operation,
-		backoff.WithContext(expBackOff, ctx),
-		func(err error, t time.Duration) {
-			log.Sugar.Errorw("error creating miner transfer, retrying",
+	lt := &db.LedgerTxn{
+		Kind: db.LedgerKindMinerPayout,
+		Job:  jUUID,
+		Entries: []db.LedgerEntry{
+			{Account: db.LedgerMinerPayable, Owner: aUUID, Amount: jf.MinerAmount},
+			{Account: db.LedgerMinerCollateral, Owner: aUUID, Amount: -hold},
+		},
+	}
+	payout := jf.MinerAmount - hold
+	if payout == 0 {
+		// the platform fee and collateral took the whole job amount, so there's nothing to transfer
+		if err := db.SetPaymentsMinerPaid(jUUID, "", 0, ch, lt); err != nil {
+			log.Sugar.Errorw("error setting payments miner paid",
 				"method", r.Method,
 				"url", r.URL,
 				"err", err.Error(),
 				"jID", jUUID,
 			)
-		}); err != nil {
-		log.Sugar.Errorw("error creating miner transfer--aborting",
+			return err
+		}
+		return nil
+	}
+
+	params := &stripe.TransferParams{
+		Destination:   stripe.String(stripeAccountID),
+		Amount:        stripe.Int64(payout),
+		Currency:      stripe.String(string(stripe.CurrencyUSD)),
+		TransferGroup: stripe.String(fmt.Sprintf("Payout for job %s", jUUID.String())),
+	}
+	key, err := db.GetPaymentIdempotencyKey(db.PaymentTaskPayMiner, jUUID)
+	if err != nil {
+		return err // already logged
+	}
+	params.SetIdempotencyKey(key)
+
+	t, err := stripeTransferC.New(params)
+	if err != nil {
+		log.Sugar.Errorw("error creating miner transfer",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
 
-	if err := db.SetPaymentsMinerPaid(jUUID, t.ID, t.Amount); err != nil {
+	lt.StripeID = t.ID
+	lt.Entries = append(lt.Entries, db.LedgerEntry{Account: db.LedgerStripeBalance, Amount: -t.Amount})
+	if err := db.SetPaymentsMinerPaid(jUUID, t.ID, t.Amount, ch, lt); err != nil {
 		log.Sugar.Errorw("error setting payments miner paid",
 			"method", r.Method,
 			"url", r.URL,
 			"err", err.Error(),
 			"jID", jUUID,
 		)
-		return
+		return err
 	}
+
+	return nil
 }
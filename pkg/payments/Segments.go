@@ -0,0 +1,85 @@
+package payments
+
+import (
+	"github.com/wminshew/emrysserver/pkg/db"
+	"time"
+)
+
+// Segment names
+const (
+	SegmentQueued       = "queued"
+	SegmentSetup        = "setup"
+	SegmentRunning      = "running"
+	SegmentUnresponsive = "unresponsive"
+)
+
+// Segment is an interval of a job's life. Only running segments are billed; Amount is in cents
+type Segment struct {
+	Name   string    `json:"name"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Billed bool      `json:"billed"`
+	Amount int64     `json:"amount"`
+}
+
+// Segments splits the life of a job with metering m, up to when it ended or else now, into
+// segments: queued until its auction completed, setup while its miner downloaded its image and
+// data, running until its output was posted, and unresponsive if its miner stopped sending
+// heartbeats before then
+func Segments(m *db.JobMetering, now time.Time) []Segment {
+	end := m.EndedAt
+	if end.IsZero() {
+		end = now
+	}
+	segs := []Segment{}
+	add := func(name string, start, end time.Time, billed bool) {
+		if start.IsZero() || !end.After(start) {
+			return
+		}
+		s := Segment{Name: name, Start: start, End: end, Billed: billed}
+		if billed {
+			s.Amount = Amount(m.Rate, start, end)
+		}
+		segs = append(segs, s)
+	}
+
+	if m.AuctionCompleted.IsZero() {
+		add(SegmentQueued, m.CreatedAt, end, false)
+		return segs
+	}
+	add(SegmentQueued, m.CreatedAt, m.AuctionCompleted, false)
+
+	runStart := m.DataDownloaded
+	if m.ImageDownloaded.After(runStart) {
+		runStart = m.ImageDownloaded
+	}
+	if m.DataDownloaded.IsZero() || m.ImageDownloaded.IsZero() {
+		add(SegmentSetup, m.AuctionCompleted, end, false)
+		return segs
+	}
+	add(SegmentSetup, m.AuctionCompleted, runStart, false)
+
+	runEnd := end
+	if !m.OutputDataPosted.IsZero() && m.OutputDataPosted.Before(runEnd) {
+		runEnd = m.OutputDataPosted
+	}
+	if !m.LastHeartbeat.IsZero() {
+		if lastAlive := m.LastHeartbeat.Add(db.HeartbeatGrace); lastAlive.Before(runEnd) {
+			add(SegmentRunning, runStart, lastAlive, true)
+			add(SegmentUnresponsive, lastAlive, runEnd, false)
+			return segs
+		}
+	}
+	add(SegmentRunning, runStart, runEnd, true)
+	return segs
+}
+
+// MeteredAmount returns the amount in cents billed for a job with metering m up to when it
+// ended or else now
+func MeteredAmount(m *db.JobMetering, now time.Time) int64 {
+	var amt int64
+	for _, s := range Segments(m, now) {
+		amt += s.Amount
+	}
+	return amt
+}
@@ -0,0 +1,123 @@
+package payments
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/stripe/stripe-go/charge"
+	"github.com/stripe/stripe-go/invoiceitem"
+	"github.com/stripe/stripe-go/transfer"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WorkerPeriod is how often services run their Worker
+const WorkerPeriod = 30 * time.Second
+
+// stripe only keeps idempotency keys for 24h, so a task's retries (at most about 10h of backoff
+// plus leases) have to finish well within that or a retry could pay out twice
+const (
+	taskLease        = 5 * time.Minute
+	taskRetryBase    = 1 * time.Minute
+	taskRetryMax     = 2 * time.Hour
+	maxTaskAttempts  = 12
+	taskErrMaxLength = 1024
+)
+
+// Worker carries out queued payment tasks. It only claims the kinds of task it has a stripe
+// client for, so each service can run one with the clients it already has
+type Worker struct {
+	InvoiceItemC *invoiceitem.Client
+	TransferC    *transfer.Client
+	ChargeC      *charge.Client
+}
+
+// Run carries out due payment tasks until there are none left. Failed tasks are retried with
+// exponential backoff, capped at taskRetryMax, and dead-lettered after maxTaskAttempts
+func (wk *Worker) Run() error {
+	kinds := []db.PaymentTaskKind{}
+	if wk.InvoiceItemC != nil {
+		kinds = append(kinds, db.PaymentTaskChargeUser)
+	}
+	if wk.TransferC != nil {
+		kinds = append(kinds, db.PaymentTaskPayMiner, db.PaymentTaskReleaseCollateral)
+	}
+	if wk.ChargeC != nil {
+		kinds = append(kinds, db.PaymentTaskChargeMiner, db.PaymentTaskRechargeCredit)
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	for {
+		t, err := db.ClaimPaymentTask(kinds, taskLease)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := wk.do(t); err != nil {
+			dead := t.Attempts >= maxTaskAttempts
+			backoff := taskRetryBase << uint(t.Attempts-1)
+			if backoff > taskRetryMax {
+				backoff = taskRetryMax
+			}
+			retryAt := time.Now().Add(backoff)
+			errMsg := err.Error()
+			if len(errMsg) > taskErrMaxLength {
+				errMsg = errMsg[:taskErrMaxLength]
+			}
+			if dead {
+				log.Sugar.Errorw("payment task failed--dead-lettering",
+					"taskID", t.ID,
+					"kind", t.Kind,
+					"attempts", t.Attempts,
+					"err", errMsg,
+					"jID", t.Job,
+				)
+			} else {
+				log.Sugar.Infow("payment task failed, retrying",
+					"taskID", t.ID,
+					"kind", t.Kind,
+					"attempts", t.Attempts,
+					"retryAt", retryAt,
+					"err", errMsg,
+					"jID", t.Job,
+				)
+			}
+			if err := db.SetPaymentTaskFailed(t.ID, errMsg, retryAt, dead); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := db.SetPaymentTaskDone(t.ID); err != nil {
+			return err
+		}
+	}
+}
+
+// do makes a single attempt at payment task t
+func (wk *Worker) do(t *db.PaymentTask) error {
+	// payment functions log against a request; tasks run outside of one
+	r := &http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Path: fmt.Sprintf("/payments/task/%d/%s", t.ID, t.Kind)},
+	}
+	switch t.Kind {
+	case db.PaymentTaskChargeUser:
+		return ChargeUser(r, wk.InvoiceItemC, t.Job)
+	case db.PaymentTaskPayMiner:
+		return PayMiner(r, wk.TransferC, t.Job)
+	case db.PaymentTaskChargeMiner:
+		return ChargeMiner(wk.ChargeC, t.Job)
+	case db.PaymentTaskReleaseCollateral:
+		return ReleaseCollateral(r, wk.TransferC, t.Job)
+	case db.PaymentTaskRechargeCredit:
+		return RechargeCredit(r, wk.ChargeC, t.Job)
+	}
+	return fmt.Errorf("unknown payment task kind %s", t.Kind)
+}
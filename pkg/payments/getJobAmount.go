@@ -6,22 +6,20 @@ import (
 	"github.com/wminshew/emrysserver/pkg/db"
 	"github.com/wminshew/emrysserver/pkg/log"
 	"math"
+	"time"
 )
 
 const minJobAmt = 1
 
+// getJobAmount returns the amount in cents billed for ended job jUUID's running time
 func getJobAmount(jUUID uuid.UUID) (int64, error) {
-	rate, createdAt, completedAt, canceledAt, failedAt, err := db.GetJobPaymentInfo(jUUID)
+	m, err := db.GetJobMetering(jUUID)
 	if err != nil {
-		log.Sugar.Errorw("error getting job payment info",
-			"err", err.Error(),
-			"jID", jUUID,
-		)
-		return 0, err
+		return 0, err // already logged
 	}
 
-	if rate == 0 || createdAt.IsZero() {
-		message := "error no job rate or created_at"
+	if m.Rate == 0 || m.EndedAt.IsZero() {
+		message := "error no job rate or end"
 		err := fmt.Errorf(message)
 		log.Sugar.Errorw(message,
 			"err", err.Error(),
@@ -30,26 +28,16 @@ func getJobAmount(jUUID uuid.UUID) (int64, error) {
 		return 0, err
 	}
 
-	var amt int64
-	if !completedAt.IsZero() {
-		amt = int64(math.Round(rate * completedAt.Sub(createdAt).Hours() * 100))
-	} else if !canceledAt.IsZero() {
-		amt = int64(math.Round(rate * canceledAt.Sub(createdAt).Hours() * 100))
-	} else if !failedAt.IsZero() {
-		amt = int64(math.Round(rate * failedAt.Sub(createdAt).Hours() * 100))
-	} else {
-		message := "error no job rate or created_at"
-		err := fmt.Errorf(message)
-		log.Sugar.Errorw(message,
-			"err", err.Error(),
-			"jID", jUUID,
-		)
-		return 0, err
-	}
+	return MeteredAmount(m, m.EndedAt), nil
+}
 
+// Amount returns the amount in cents owed for a job billed at rate ($/hr) from start to end,
+// metered per second
+func Amount(rate float64, start, end time.Time) int64 {
+	seconds := math.Ceil(end.Sub(start).Seconds())
+	amt := int64(math.Round(rate * seconds / 3600 * 100))
 	if amt < minJobAmt {
 		amt = minJobAmt
 	}
-
-	return amt, nil
+	return amt
 }
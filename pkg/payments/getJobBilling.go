@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// getJobBilling returns job jUUID's owner, its org (uuid.Nil for personal jobs) and the stripe
+// customer and subscription it's billed to: the org's for org jobs, else the owner's
+func getJobBilling(r *http.Request, jUUID uuid.UUID) (uuid.UUID, uuid.UUID, string, string, error) {
+	aUUID, err := db.GetJobOwner(r, jUUID)
+	if err != nil {
+		log.Sugar.Errorw("error getting job owner",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return uuid.Nil, uuid.Nil, "", "", err
+	}
+
+	oUUID, err := db.GetJobOrg(r, jUUID)
+	if err != nil {
+		log.Sugar.Errorw("error getting job org",
+			"method", r.Method,
+			"url", r.URL,
+			"err", err.Error(),
+			"jID", jUUID,
+		)
+		return uuid.Nil, uuid.Nil, "", "", err
+	}
+
+	var stripeCustomerID, stripeSubscriptionID string
+	if uuid.Equal(oUUID, uuid.Nil) {
+		stripeCustomerID, err = db.GetAccountStripeCustomerID(r, aUUID)
+		if err != nil {
+			log.Sugar.Errorw("error getting stripe customer ID",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+			return uuid.Nil, uuid.Nil, "", "", err
+		}
+
+		stripeSubscriptionID, err = db.GetAccountStripeSubscriptionID(r, aUUID)
+		if err != nil {
+			log.Sugar.Errorw("error getting stripe subscription ID",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+			return uuid.Nil, uuid.Nil, "", "", err
+		}
+	} else {
+		_, stripeCustomerID, stripeSubscriptionID, err = db.GetOrgBilling(r, oUUID)
+		if err != nil {
+			log.Sugar.Errorw("error getting org billing",
+				"method", r.Method,
+				"url", r.URL,
+				"err", err.Error(),
+				"jID", jUUID,
+			)
+			return uuid.Nil, uuid.Nil, "", "", err
+		}
+	}
+
+	return aUUID, oUUID, stripeCustomerID, stripeSubscriptionID, nil
+}
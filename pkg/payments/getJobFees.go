@@ -0,0 +1,61 @@
+package payments
+
+import (
+	"database/sql"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"math"
+)
+
+// getJobFees returns how job jUUID's amount is split between its miner and the platform. The
+// split is computed from the fee schedule in effect when the job was created and recorded the
+// first time it's needed; later calls return the recorded split
+func getJobFees(jUUID uuid.UUID) (*db.JobFees, error) {
+	jf, err := db.GetJobFees(jUUID)
+	if err == nil {
+		return jf, nil
+	} else if err != sql.ErrNoRows {
+		return nil, err // already logged
+	}
+
+	jobAmount, err := getJobAmount(jUUID)
+	if err != nil {
+		return nil, err // already logged
+	}
+
+	gpu, tier, createdAt, err := db.GetJobFeeBasis(jUUID)
+	if err != nil {
+		return nil, err // already logged
+	}
+
+	jf = &db.JobFees{Amount: jobAmount}
+	fs, err := db.GetFeeSchedule(gpu, tier, createdAt)
+	if err == nil {
+		jf.FeeScheduleID = fs.ID
+		jf.Fee = Fee(jobAmount, fs.Percent, fs.Fixed)
+	} else if err != sql.ErrNoRows {
+		return nil, err // already logged
+	} else {
+		log.Sugar.Infow("no fee schedule applies to job",
+			"gpu", gpu,
+			"tier", tier,
+			"jID", jUUID,
+		)
+	}
+	jf.MinerAmount = jf.Amount - jf.Fee
+
+	return db.InsertJobFees(jUUID, jf)
+}
+
+// Fee returns the platform's fee in cents on a job amount (cents) under a fee schedule of
+// percent (0-1) plus fixed cents. The fee never exceeds the amount
+func Fee(amount int64, percent float64, fixed int64) int64 {
+	fee := int64(math.Round(float64(amount)*percent)) + fixed
+	if fee > amount {
+		fee = amount
+	} else if fee < 0 {
+		fee = 0
+	}
+	return fee
+}
@@ -0,0 +1,72 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"github.com/satori/go.uuid"
+	"github.com/wminshew/emrysserver/pkg/app"
+	"github.com/wminshew/emrysserver/pkg/db"
+	"github.com/wminshew/emrysserver/pkg/log"
+	"net/http"
+)
+
+// Check returns an error unless organization oUUID or, if it's uuid.Nil, account aUUID may start
+// another job: 429 at the concurrent job limit and 402 once a monthly spend or GPU-hour limit is used up
+func Check(r *http.Request, aUUID, oUUID uuid.UUID) *app.Error {
+	q, err := db.GetQuota(r, aUUID, oUUID)
+	if err != nil {
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+
+	appErr := exceeded(q)
+	if appErr != nil {
+		logExceeded(r, aUUID, oUUID, appErr)
+	}
+	return appErr
+}
+
+// Reserve is Check for job jUUID about to be auctioned: the check runs under a lock on the
+// account or organization and, if it passes, jUUID counts as a concurrent job straight away
+func Reserve(r *http.Request, aUUID, oUUID, jUUID uuid.UUID) *app.Error {
+	var appErr *app.Error
+	if err := db.ReserveJobAuction(r, aUUID, oUUID, jUUID, func(q *db.Quota) error {
+		if appErr = exceeded(q); appErr != nil {
+			return errors.New(appErr.Message)
+		}
+		return nil
+	}); err != nil {
+		if appErr != nil {
+			logExceeded(r, aUUID, oUUID, appErr)
+			return appErr
+		}
+		return &app.Error{Code: http.StatusInternalServerError, Message: "internal error"} // already logged
+	}
+	return nil
+}
+
+func exceeded(q *db.Quota) *app.Error {
+	if q.MaxConcurrentJobs > 0 && q.ConcurrentJobs >= q.MaxConcurrentJobs {
+		return &app.Error{Code: http.StatusTooManyRequests,
+			Message: fmt.Sprintf("concurrent job limit reached (%d running of %d). "+
+				"Please wait for a job to finish or reach out to support to raise your limit.", q.ConcurrentJobs, q.MaxConcurrentJobs)}
+	} else if q.MaxMonthlySpend > 0 && q.MonthlySpend >= q.MaxMonthlySpend {
+		return &app.Error{Code: http.StatusPaymentRequired,
+			Message: fmt.Sprintf("monthly spend limit reached ($%.2f of $%.2f). "+
+				"Please reach out to support to raise your limit.", float64(q.MonthlySpend)/100, float64(q.MaxMonthlySpend)/100)}
+	} else if q.MaxMonthlyGPUHours > 0 && q.MonthlyGPUHours >= q.MaxMonthlyGPUHours {
+		return &app.Error{Code: http.StatusPaymentRequired,
+			Message: fmt.Sprintf("monthly GPU-hour limit reached (%.1f of %.1f). "+
+				"Please reach out to support to raise your limit.", q.MonthlyGPUHours, q.MaxMonthlyGPUHours)}
+	}
+	return nil
+}
+
+func logExceeded(r *http.Request, aUUID, oUUID uuid.UUID, appErr *app.Error) {
+	log.Sugar.Infow("quota exceeded",
+		"method", r.Method,
+		"url", r.URL,
+		"aID", aUUID,
+		"oID", oUUID,
+		"err", appErr.Message,
+	)
+}
@@ -0,0 +1,10 @@
+package storage
+
+import (
+	"context"
+)
+
+// Delete deletes the object at p from the emrys-dev bucket
+func Delete(ctx context.Context, p string) error {
+	return bkt.Object(p).Delete(ctx)
+}